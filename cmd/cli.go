@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"strconv"
+	"strings"
+)
+
+// errReported indica que el subcomando ya ha mostrado sus errores: sólo hay que
+// terminar con código 1
+var errReported = errors.New("error ya mostrado")
+
+// flagSet es un flag.FlagSet con opciones largas y cortas (--profile y -p) y la
+// ayuda propia de cada subcomando
+type flagSet struct {
+	fs      *flag.FlagSet
+	name    string
+	args    string
+	summary string
+	help    []flagHelp
+}
+
+type flagHelp struct {
+	long, short, arg, text string
+	def                    string // Valor por defecto, si no es el vacío
+}
+
+// newFlagSet crea las opciones del subcomando name; args describe los argumentos
+// (p.ej. "<archivo>...") y summary lo que hace. Los textos de la ayuda se escriben en
+// español y se traducen al mostrarla.
+func newFlagSet(name, args, summary string) *flagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return &flagSet{fs: fs, name: name, args: args, summary: summary}
+}
+
+// String define una opción con valor; short puede estar vacío
+func (f *flagSet) String(long, short, arg, def, text string) *string {
+	p := f.fs.String(long, def, text)
+	if short != "" {
+		f.fs.StringVar(p, short, def, text)
+	}
+	f.help = append(f.help, flagHelp{long, short, arg, text, def})
+	return p
+}
+
+// Int define una opción numérica; short puede estar vacío
+func (f *flagSet) Int(long, short, arg string, def int, text string) *int {
+	p := f.fs.Int(long, def, text)
+	if short != "" {
+		f.fs.IntVar(p, short, def, text)
+	}
+	h := flagHelp{long, short, arg, text, ""}
+	if def != 0 {
+		h.def = strconv.Itoa(def)
+	}
+	f.help = append(f.help, h)
+	return p
+}
+
+// Bool define una opción sin valor; short puede estar vacío
+func (f *flagSet) Bool(long, short, text string) *bool {
+	p := f.fs.Bool(long, false, text)
+	if short != "" {
+		f.fs.BoolVar(p, short, false, text)
+	}
+	f.help = append(f.help, flagHelp{long, short, "", text, ""})
+	return p
+}
+
+// Parse lee las opciones, que pueden ir antes o después de los argumentos, y
+// devuelve los argumentos. Con -h/--help muestra la ayuda y devuelve flag.ErrHelp.
+func (f *flagSet) Parse(args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := f.fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				f.Usage()
+				return nil, err
+			}
+			msg := err.Error()
+			if name, ok := strings.CutPrefix(msg, "flag provided but not defined: "); ok {
+				msg = fmt.Sprintf(i18n.T("opción desconocida: %s"), name)
+			} else if name, ok := strings.CutPrefix(msg, "flag needs an argument: "); ok {
+				msg = fmt.Sprintf(i18n.T("falta el valor de %s"), name)
+			}
+			return nil, fmt.Errorf(i18n.T("%s (use mediacraft %s -h)"), msg, f.name)
+		}
+		args = f.fs.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// Usage muestra la ayuda del subcomando
+func (f *flagSet) Usage() {
+	fmt.Fprintf(logging.Stdout, i18n.T("Uso: %s\n"), strings.TrimSpace("mediacraft "+f.name+i18n.T(" [opciones] ")+i18n.T(f.args)))
+	fmt.Fprintf(logging.Stdout, "  %s\n", i18n.T(f.summary))
+	if len(f.help) == 0 {
+		return
+	}
+	fmt.Fprint(logging.Stdout, i18n.T("\nOpciones:\n"))
+	for _, h := range f.help {
+		name := "    --" + h.long
+		if h.short != "" {
+			name = "-" + h.short + ", --" + h.long
+		}
+		if h.arg != "" {
+			name += " <" + i18n.T(h.arg) + ">"
+		}
+		text := i18n.T(h.text)
+		if h.def != "" {
+			text += fmt.Sprintf(i18n.T(" (por defecto %s)"), h.def)
+		}
+		fmt.Fprintf(logging.Stdout, "  %-28s %s\n", name, text)
+	}
+}
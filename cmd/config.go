@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"os"
+	"strings"
+)
+
+// runConfig implementa "mediacraft config": por ahora sólo convert
+func runConfig(_ *config.Config, args []string) error {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		newFlagSet("config", "convert <archivo> [salida] [--to yaml|toml|json|ini]",
+			"Convierte un archivo de configuración a otro formato (sin salida, lo escribe por pantalla)").Usage()
+		return flag.ErrHelp
+	}
+	if len(args) == 0 || args[0] != "convert" {
+		return errors.New(i18n.T("uso: mediacraft config convert <archivo> [salida] [--to yaml|toml|json|ini]"))
+	}
+	return configConvert(args[1:])
+}
+
+// configConvert pasa un archivo de configuración a otro formato. Con salida, el formato
+// sale de su extensión (salvo --to) y no se sobrescribe un archivo existente; sin ella,
+// se escribe en la salida estándar (YAML por defecto).
+func configConvert(args []string) error {
+	var files []string
+	to := ""
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "--to" && i+1 < len(args):
+			to = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--to="):
+			to = strings.TrimPrefix(a, "--to=")
+		default:
+			files = append(files, a)
+		}
+	}
+	if len(files) == 0 || len(files) > 2 {
+		return errors.New(i18n.T("uso: mediacraft config convert <archivo> [salida] [--to yaml|toml|json|ini]"))
+	}
+	if to == "" {
+		to = config.FormatYAML
+		if len(files) == 2 {
+			to = config.FormatOf(files[1])
+		}
+	}
+	data, err := config.Convert(files[0], strings.ToLower(to))
+	if err != nil {
+		return err
+	}
+	if len(files) == 1 {
+		_, err = logging.Stdout.Write(data)
+		return err
+	}
+	out := files[1]
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf(i18n.T("%s ya existe; no se sobrescribe"), out)
+	}
+	if err := os.WriteFile(out, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(logging.Stdout, i18n.T("\033[32mConfiguración convertida: %s → %s (%s)\033[0m\n"), files[0], out, to)
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"mediacraft/config"
+	"mediacraft/decompress"
+	"mediacraft/encode"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/order"
+	"mediacraft/progress"
+	"mediacraft/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// runConvert implementa "mediacraft convert"
+func runConvert(cfg *config.Config, args []string) error {
+	flags := newFlagSet("convert", "<archivo|carpeta>...", "Convierte vídeos, archivos comprimidos o carpetas enteras con un perfil")
+	profile := flags.String("profile", "p", "perfil", "", "Perfil o lista de perfiles (telegram,plex,audio); si no, archivo@perfil, .mediacraft.conf o default_profile")
+	output := flags.String("output", "o", "ruta", "", "Plantilla, archivo o carpeta de salida")
+	workers := flags.Int("workers", "w", "n", 1, "Conversiones simultáneas")
+	dryRun := flags.Bool("dry-run", "n", "Mostrar los comandos de ffmpeg sin ejecutarlos")
+	paths, err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New(i18n.T("falta el archivo o la carpeta a convertir (use mediacraft convert -h)"))
+	}
+	if *workers <= 0 {
+		return errors.New(i18n.T("--workers: se esperaba un número positivo"))
+	}
+	for _, p := range strings.Split(*profile, ",") {
+		if p = strings.TrimSpace(p); p != "" && !cfg.HasProfile(p) {
+			return fmt.Errorf(i18n.T("perfil desconocido: %s (disponibles: %s)"), p, strings.Join(cfg.ProfileNames(), ", "))
+		}
+	}
+	inputs, err := expandInputs(paths)
+	if err != nil {
+		return err
+	}
+	opts := encode.Options{Profile: *profile, Output: *output, Config: cfg, DryRun: *dryRun}
+	if !*dryRun {
+		for _, in := range inputs {
+			logging.Emit(logging.Event{Type: logging.EventQueued, Input: in, Profile: *profile})
+		}
+	}
+	// Las barras de todas las conversiones, con la del lote si hay varios archivos
+	if !*dryRun {
+		opts.Renderer = progress.New(len(inputs))
+		opts.Renderer.Start()
+		defer opts.Renderer.Stop()
+	}
+	failed := 0
+	if *workers == 1 || len(inputs) == 1 {
+		for _, in := range inputs {
+			err := encode.ConvertWith(in, opts)
+			if err != nil {
+				failed++
+			}
+			opts.Renderer.FileDone(err == nil)
+		}
+	} else {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, *workers)
+		for _, in := range inputs {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(in string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				err := encode.ConvertWith(in, opts)
+				if err != nil {
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+				opts.Renderer.FileDone(err == nil)
+			}(in)
+		}
+		wg.Wait()
+	}
+	if failed == 0 {
+		return nil
+	}
+	if len(inputs) == 1 {
+		return errReported
+	}
+	return fmt.Errorf(i18n.T("fallaron %d de %d conversiones"), failed, len(inputs))
+}
+
+// expandInputs sustituye cada carpeta por los vídeos y archivos comprimidos que
+// contiene (de un archivo partido, sólo su primer volumen). Los archivos, y las rutas
+// con sufijo @perfil, se dejan tal cual.
+func expandInputs(paths []string) ([]string, error) {
+	var res []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.IsDir() {
+			res = append(res, p)
+			continue
+		}
+		n := len(res)
+		err = filepath.WalkDir(p, func(f string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			switch {
+			case utils.IsVideoFile(f):
+				res = append(res, f)
+			case decompress.IsCompressed(f):
+				if parts, _ := decompress.ArchiveParts(f); parts[0] == f {
+					res = append(res, f)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == n {
+			log.Warnf("%s: no hay vídeos ni archivos comprimidos", p)
+		}
+	}
+	if len(res) == 0 {
+		return nil, errors.New(i18n.T("no hay nada que convertir"))
+	}
+	return res, nil
+}
+
+// runOrder implementa "mediacraft order"
+func runOrder(cfg *config.Config, args []string) error {
+	flags := newFlagSet("order", "<carpeta>...", "Ordena los episodios de cada carpeta en subcarpetas \"Temporada N\"")
+	dryRun := flags.Bool("dry-run", "n", "Mostrar qué se movería, sin mover nada")
+	dirs, err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return errors.New(i18n.T("falta la carpeta a ordenar (use mediacraft order -h)"))
+	}
+	var errs []error
+	for _, d := range dirs {
+		if err := order.OrderSeriesWith(cfg, d, order.Options{DryRun: *dryRun}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runExtract implementa "mediacraft extract"
+func runExtract(_ *config.Config, args []string) error {
+	flags := newFlagSet("extract", "<archivo>...", "Extrae archivos comprimidos (también partidos en volúmenes) con 7z")
+	output := flags.String("output", "o", "carpeta", "", "Carpeta de destino (por defecto, una con el nombre del archivo junto a él)")
+	dryRun := flags.Bool("dry-run", "n", "Mostrar qué se extraería y dónde, sin extraer nada")
+	archives, err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(archives) == 0 {
+		return errors.New(i18n.T("falta el archivo a extraer (use mediacraft extract -h)"))
+	}
+	blue := "\033[34m"
+	green := "\033[32m"
+	reset := "\033[0m"
+	var errs []error
+	for _, a := range archives {
+		if !decompress.IsCompressed(a) {
+			errs = append(errs, fmt.Errorf(i18n.T("%s: no es un archivo comprimido"), a))
+			continue
+		}
+		dest := *output
+		if dest == "" {
+			dest = archiveDest(a)
+		}
+		parts, _ := decompress.ArchiveParts(a)
+		if *dryRun {
+			fmt.Fprintf(logging.Stdout, i18n.T("  (ensayo) %s (%d volúmenes) → %s\n"), a, len(parts), dest)
+			continue
+		}
+		log.Infof("%s  Extrayendo %s → %s%s\n", blue, filepath.Base(a), dest, reset)
+		if err := decompress.ExtractTo(a, dest); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a, err))
+			continue
+		}
+		log.Infof("%s  Extraído: %s%s\n", green, dest, reset)
+	}
+	return errors.Join(errs...)
+}
+
+// archiveDest devuelve la carpeta de extracción por defecto: junto al archivo y con su
+// nombre sin las extensiones de compresión (serie.part01.rar → serie)
+func archiveDest(path string) string {
+	name := filepath.Base(path)
+	for i := 0; i < 2 && decompress.IsCompressed(name); i++ {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return filepath.Join(filepath.Dir(path), name)
+}
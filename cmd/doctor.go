@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/encode"
+	"mediacraft/history"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/platform"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runDoctor implementa "mediacraft doctor": comprueba las herramientas externas, la GPU,
+// la configuración y los perfiles, y explica cómo resolver lo que falle
+func runDoctor(cfg *config.Config, args []string) error {
+	flags := newFlagSet("doctor", "", "Comprueba ffmpeg, ffprobe, 7z, la GPU, la configuración y los perfiles")
+	if _, err := flags.Parse(args); err != nil {
+		return err
+	}
+	green := "\033[32m"
+	yellow := "\033[33m"
+	red := "\033[31m"
+	reset := "\033[0m"
+	errorsFound := 0
+	ok := func(what, detail string) {
+		fmt.Fprintf(logging.Stdout, "%s  OK     %s%s  %s\n", green, what, reset, detail)
+	}
+	warn := func(what, detail string) {
+		fmt.Fprintf(logging.Stdout, i18n.T("%s  AVISO  %s%s  %s\n"), yellow, what, reset, detail)
+	}
+	fail := func(what, detail string) {
+		fmt.Fprintf(logging.Stdout, "%s  ERROR  %s%s  %s\n", red, what, reset, detail)
+		errorsFound++
+	}
+
+	// Herramientas externas: las de [herramientas] o las del PATH
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		path, err := platform.LookTool(tool)
+		if err != nil {
+			fail(tool, err.Error()+i18n.T("; instálelo (https://ffmpeg.org)"))
+			continue
+		}
+		ok(tool, toolVersion(path)+" ("+path+")")
+	}
+	if path, err := platform.LookTool("7z"); err == nil {
+		ok("7z", path)
+	} else {
+		warn("7z", err.Error()+i18n.T("; no se podrán extraer archivos comprimidos"))
+	}
+	// Carpeta temporal: extracciones y estadísticas de las dos pasadas
+	if f, err := os.CreateTemp("", "mediacraft-doctor-"); err == nil {
+		f.Close()
+		os.Remove(f.Name())
+		ok(i18n.T("Temporal"), os.TempDir())
+	} else {
+		fail(i18n.T("Temporal"), fmt.Sprintf(i18n.T("no se puede escribir en %s: %v"), os.TempDir(), err))
+	}
+
+	// GPU
+	if encode.NvidiaAvailable() {
+		ok("GPU", i18n.T("NVIDIA detectada: se usará NVENC"))
+	} else {
+		warn("GPU", i18n.T("sin GPU NVIDIA: los perfiles NVENC usarán codificadores por software"))
+	}
+
+	// Configuración
+	ok(i18n.T("configuración"), strings.Join(cfg.Sources, ", "))
+	for _, w := range cfg.Warnings {
+		warn(i18n.T("configuración"), w)
+	}
+	if !cfg.HasProfile(cfg.DefaultProfile) {
+		fail(i18n.T("configuración"), fmt.Sprintf(i18n.T("default_profile = %s no existe"), cfg.DefaultProfile))
+	}
+	if p, err := history.Path(); err == nil && cfg.EnableHistory {
+		ok(i18n.T("historial"), p)
+	}
+
+	// Perfiles contra el ffmpeg instalado
+	if caps, err := encode.LoadCapabilities(); err == nil {
+		valid := 0
+		for _, name := range cfg.ProfileNames() {
+			r, err := encode.ResolveProfile(cfg, name)
+			if err != nil {
+				fail(i18n.T("perfil ")+name, err.Error())
+				continue
+			}
+			if problems := r.Validate(caps); len(problems) > 0 {
+				warn(i18n.T("perfil ")+name, strings.Join(problems, "; "))
+				continue
+			}
+			valid++
+		}
+		ok(i18n.T("perfiles"), fmt.Sprintf(i18n.T("%d de %d válidos con el ffmpeg instalado"), valid, len(cfg.ProfileNames())))
+	}
+
+	if errorsFound > 0 {
+		return fmt.Errorf(i18n.T("%d problemas encontrados"), errorsFound)
+	}
+	return nil
+}
+
+// toolVersion devuelve la versión de ffmpeg/ffprobe (primera línea de -version)
+func toolVersion(path string) string {
+	out, err := exec.Command(path, "-version").Output()
+	if err != nil {
+		return i18n.T("versión desconocida")
+	}
+	line := strings.SplitN(string(out), "\n", 2)[0]
+	if f := strings.Fields(line); len(f) >= 3 {
+		return f[0] + " " + f[2]
+	}
+	return line
+}
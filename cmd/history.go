@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/history"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+)
+
+// runHistory implementa "mediacraft history": lista, filtra y exporta el historial
+func runHistory(_ *config.Config, args []string) error {
+	fs := newFlagSet("history", "", "Lista, filtra y exporta el historial de trabajos")
+	jobType := fs.String("type", "t", "tipo", "", "Filtrar por tipo (convert, order)")
+	profile := fs.String("profile", "p", "perfil", "", "Filtrar por perfil")
+	status := fs.String("status", "s", "estado", "", "Filtrar por estado (ok, failed, canceled, skipped)")
+	since := fs.String("since", "", "fecha", "", "Sólo trabajos desde la fecha (AAAA-MM-DD)")
+	until := fs.String("until", "", "fecha", "", "Sólo trabajos anteriores a la fecha (AAAA-MM-DD)")
+	format := fs.String("format", "f", "formato", "table", "Formato de salida: table, json o csv")
+	limit := fs.Int("limit", "n", "n", 0, "Mostrar sólo los N trabajos más recientes")
+	if _, err := fs.Parse(args); err != nil {
+		return err
+	}
+	filter := history.Filter{Type: *jobType, Profile: *profile, Status: *status}
+	var err error
+	if *since != "" {
+		if filter.Since, err = history.ParseDate(*since); err != nil {
+			return err
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = history.ParseDate(*until); err != nil {
+			return err
+		}
+	}
+	entries, err := history.Load()
+	if err != nil {
+		return err
+	}
+	entries = filter.Apply(entries)
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+	switch *format {
+	case "json":
+		return history.WriteJSON(logging.Stdout, entries)
+	case "csv":
+		return history.WriteCSV(logging.Stdout, entries)
+	case "table":
+		history.WriteTable(logging.Stdout, entries)
+		return nil
+	default:
+		return fmt.Errorf(i18n.T("formato desconocido: %s"), *format)
+	}
+}
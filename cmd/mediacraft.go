@@ -1,19 +1,17 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"mediacraft/config"
-	"mediacraft/encode"
-	"mediacraft/order"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/platform"
+	"mediacraft/server"
+	"mediacraft/watch"
 	"os"
-)
-
-var (
-	convertFlag = flag.String("c", "", "Convertir archivo o carpeta de videos")
-	orderFlag   = flag.String("o", "", "Ordenar archivos de series")
-	versionFlag = flag.Bool("v", false, "Mostrar versión")
-	helpFlag    = flag.Bool("h", false, "Mostrar ayuda")
+	"strings"
 )
 
 const version = "v1.0.0"
@@ -21,71 +19,305 @@ const projectName = "Nostromo"
 const author = "JorgeMFB"
 const releaseDate = "25 de julio de 2025 (primera versión estable)"
 
+// command es un subcomando de mediacraft
+type command struct {
+	name    string
+	args    string
+	summary string
+	config  bool // Necesita la configuración cargada
+	run     func(cfg *config.Config, args []string) error
+}
+
+var commands = []command{
+	{"convert", "<archivo|carpeta>...", "Convertir vídeos (--profile, --output, --workers, --dry-run)", true, runConvert},
+	{"order", "<carpeta>...", "Ordenar episodios en carpetas de temporada (--dry-run)", true, runOrder},
+	{"extract", "<archivo>...", "Extraer archivos comprimidos o partidos (--output, --dry-run)", false, runExtract},
+	{"probe", "<archivo|carpeta>...", "Contenedor, pistas y perfil que se aplicaría (--format json)", true, runProbe},
+	{"profiles", "[acción]", "Perfiles: list, show <perfil>, validate [perfil...], diff <a> <b>", true, runProfiles},
+	{"history", "", "Historial de trabajos (--profile, --status, --since, --format json|csv)", false, runHistory},
+	{"doctor", "", "Comprobar herramientas, GPU, configuración y perfiles", true, runDoctor},
+	{"watch", "<carpeta>", "Vigilar una carpeta y procesar lo que llegue", true, runWatch},
+	{"serve", "[dirección]", "Servidor HTTP con cola de trabajos y panel web", true, runServe},
+	{"config", "convert <archivo>", "Pasar la configuración a YAML, TOML o JSON", false, runConfig},
+}
+
+var log = logging.For("mediacraft")
+
+// globalOptions son las opciones válidas con cualquier subcomando
+type globalOptions struct {
+	load    config.LoadOptions
+	verbose bool   // --verbose: también los mensajes de depuración
+	quiet   bool   // --quiet: sólo los errores
+	logFile string // --log-file: copia de todos los mensajes, con fecha y nivel
+	format  string // --output-format: text o json (eventos, uno por línea)
+}
+
 func main() {
-	// Preprocesar flags largos para compatibilidad con el paquete flag
-	var newArgs []string
-	for i := 0; i < len(os.Args); i++ {
-		arg := os.Args[i]
-		switch arg {
-		case "--help":
-			newArgs = append(newArgs, "-h")
-		case "--version":
-			newArgs = append(newArgs, "-v")
-		case "--convert":
-			newArgs = append(newArgs, "-c")
-			if i+1 < len(os.Args) {
-				newArgs = append(newArgs, os.Args[i+1])
-				i++ // saltar el valor
+	// Opciones globales, válidas con cualquier subcomando
+	var global globalOptions
+	args := globalArgs(os.Args, &global)[1:]
+	// El idioma (idioma o LANG) se elige antes de cargar la configuración, para que
+	// sus errores salgan ya traducidos. La configuración se carga antes que nada por
+	// las rutas de [herramientas], que valen para todo el proceso; si falla, el error
+	// sólo se muestra en los subcomandos que la necesitan.
+	i18n.Set(config.Language(global.load))
+	loaded, loadErr := config.Load(global.load)
+	if loadErr == nil {
+		platform.SetToolPaths(loaded.Tools)
+	}
+	if err := setupLogging(global); err != nil {
+		fmt.Fprintf(logging.Stdout, "\033[31m[ERROR] %v\033[0m\n", err)
+		os.Exit(1)
+	}
+	defer logging.Close()
+
+	if len(args) == 0 {
+		red := "\033[31m"
+		reset := "\033[0m"
+		fmt.Fprintf(logging.Stdout, i18n.T("%sNo se especificó ninguna acción.%s\n"), red, reset)
+		fmt.Fprint(logging.Stdout, i18n.T("Use -h o --help para ver las opciones.\n"))
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "-h", "--help", "help":
+		if len(args) == 1 {
+			printHelp()
+			return
+		}
+		args = []string{args[1], "-h"}
+	case "-v", "--version", "version":
+		printVersion()
+		return
+	}
+	// Formas antiguas: -c/--convert <ruta>, -o/--order <carpeta>
+	if strings.HasPrefix(args[0], "-") {
+		name, rest, ok := legacyArgs(args)
+		if !ok {
+			fmt.Fprintf(logging.Stdout, i18n.T("\033[31m[ERROR] opción desconocida: %s\033[0m\n"), args[0])
+			fmt.Fprint(logging.Stdout, i18n.T("Use -h o --help para ver las opciones.\n"))
+			os.Exit(1)
+		}
+		args = append([]string{name}, rest...)
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(logging.Stdout, i18n.T("\033[31m[ERROR] subcomando desconocido: %s\033[0m\n"), args[0])
+		fmt.Fprint(logging.Stdout, i18n.T("Use -h o --help para ver las opciones.\n"))
+		os.Exit(1)
+	}
+	var cfg *config.Config
+	if cmd.config && !wantsHelp(args[1:]) {
+		cfg = checkConfig(loaded, loadErr)
+	}
+	if err := cmd.run(cfg, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if !errors.Is(err, errReported) {
+			log.Errorf("%v", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// wantsHelp indica si se pide la ayuda del subcomando (que no necesita la configuración)
+func wantsHelp(args []string) bool {
+	for _, a := range args {
+		if a == "-h" || a == "--help" || a == "-help" {
+			return true
+		}
+	}
+	return false
+}
+
+func printHelp() {
+	fmt.Fprint(logging.Stdout, i18n.T("   MediaCraft CLI - Ayuda\n"))
+	fmt.Fprint(logging.Stdout, i18n.T(" Uso: mediacraft <subcomando> [opciones] [argumentos]\n\n"))
+	fmt.Fprint(logging.Stdout, i18n.T(" Subcomandos:\n"))
+	for _, c := range commands {
+		fmt.Fprintf(logging.Stdout, "   %-9s %-21s %s\n", c.name, i18n.T(c.args), i18n.T(c.summary))
+	}
+	fmt.Fprint(logging.Stdout, i18n.T("\n Opciones globales:\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   --config <archivo>          Archivo de configuración (también MEDIACRAFT_CONFIG)\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   --set <seccion.clave=valor> Sobrescribir una opción de la configuración\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   --verbose                   Mostrar también los mensajes de depuración (comandos, rutas)\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   --quiet                     Mostrar sólo los errores\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   --log-file <archivo>        Añadir todos los mensajes, con fecha y nivel, al archivo\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   --output-format <formato>   text o json (un evento JSON por línea: cola, progreso, fin, error, movido)\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   -v, --version               Mostrar versión\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   -h, --help [subcomando]     Mostrar ayuda (también mediacraft <subcomando> -h)\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("\n Formas abreviadas (compatibles con versiones anteriores):\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   -c, --convert <ruta>        Igual que convert <ruta> (admite -o/--output)\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("   -o, --order <carpeta>       Igual que order <carpeta>\n"))
+	fmt.Fprint(logging.Stdout, i18n.T("\n Idioma de los mensajes: idioma = es|en en la configuración o LANG\n"))
+}
+
+func printVersion() {
+	cyan := "\033[36m"
+	reset := "\033[0m"
+	fmt.Fprintf(logging.Stdout, "%s   MediaCraft %s (%s) %s\n", cyan, version, projectName, reset)
+	fmt.Fprintf(logging.Stdout, i18n.T("    Autor: %s\n"), author)
+	fmt.Fprintf(logging.Stdout, i18n.T("    Fecha: %s\n"), i18n.T(releaseDate))
+}
+
+// legacyArgs traduce las formas antiguas -c/--convert <ruta> y -o/--order <carpeta>
+// (también con =) al subcomando equivalente. El resto de opciones (--output) pasan al
+// subcomando. Junto a -c, -o es la salida (-c archivo -o carpeta/), como en convert;
+// con --convert y --order gana --convert, como antes.
+func legacyArgs(args []string) (string, []string, bool) {
+	var convertPath, orderPath string
+	var shortO, rest []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") {
+			name = ""
+		}
+		switch name {
+		case "c", "convert", "o", "order":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return "", nil, false
+				}
+				value = args[i+1]
+				i++
 			}
-		case "--order":
-			newArgs = append(newArgs, "-o")
-			if i+1 < len(os.Args) {
-				newArgs = append(newArgs, os.Args[i+1])
-				i++ // saltar el valor
+			switch name {
+			case "c", "convert":
+				convertPath = value
+			case "o":
+				shortO = append(shortO, value)
+				orderPath = value
+			default:
+				orderPath = value
 			}
 		default:
-			if i != 0 { // omitir el nombre del ejecutable
-				newArgs = append(newArgs, arg)
-			}
+			rest = append(rest, a)
 		}
 	}
-	os.Args = append([]string{os.Args[0]}, newArgs...)
-	flag.Parse()
-
-	if *helpFlag {
-		fmt.Printf("   MediaCraft CLI - Ayuda\n")
-		fmt.Printf(" Uso: %s [flags]\n", projectName)
-		fmt.Printf(" -c, --convert   Convertir archivo o carpeta de videos\n")
-		fmt.Printf(" -o, --order     Ordenar archivos de series\n")
-		fmt.Printf(" -v, --version   Mostrar versión\n")
-		fmt.Printf(" -h, --help      Mostrar ayuda\n")
-		os.Exit(0)
+	if convertPath != "" {
+		for _, o := range shortO {
+			rest = append(rest, "--output", o)
+		}
+		return "convert", append([]string{convertPath}, rest...), true
 	}
+	if orderPath != "" {
+		return "order", append([]string{orderPath}, rest...), true
+	}
+	return "", nil, false
+}
 
-	if *versionFlag {
-		cyan := "\033[36m"
-		reset := "\033[0m"
-		fmt.Printf("%s   MediaCraft %s (%s) %s\n", cyan, version, projectName, reset)
-		fmt.Printf("    Autor: %s\n", author)
-		fmt.Printf("    Fecha: %s\n", releaseDate)
-		os.Exit(0)
+// runWatch implementa "mediacraft watch"
+func runWatch(cfg *config.Config, args []string) error {
+	fs := newFlagSet("watch", "<carpeta>", "Vigila la carpeta y convierte u ordena lo que llegue (sección [watch])")
+	dirs, err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(dirs) != 1 {
+		return errors.New(i18n.T("uso: mediacraft watch <carpeta>"))
 	}
+	return watch.Watch(cfg, dirs[0])
+}
 
-	config.LoadProfiles()
+// runServe implementa "mediacraft serve"
+func runServe(cfg *config.Config, args []string) error {
+	fs := newFlagSet("serve", "[dirección]", "Servidor HTTP con cola de trabajos y panel web (sección [servidor])")
+	rest, err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	addr := ""
+	if len(rest) > 0 {
+		addr = rest[0]
+	}
+	return server.Serve(cfg, addr)
+}
 
-	if *convertFlag != "" {
-		encode.Convert(*convertFlag)
-		return
+// checkConfig muestra los avisos de la configuración cargada; termina el programa si
+// no se pudo cargar
+func checkConfig(cfg *config.Config, err error) *config.Config {
+	if err != nil {
+		log.Errorf("%v", err)
+		os.Exit(1)
+	}
+	for _, w := range cfg.Warnings {
+		log.Warnf("%s", w)
 	}
+	log.Debugf("Configuración: %s", strings.Join(cfg.Sources, ", "))
+	return cfg
+}
 
-	if *orderFlag != "" {
-		order.OrderSeries(*orderFlag)
-		os.Exit(0)
+// globalArgs extrae de los argumentos las opciones globales: --config <archivo>,
+// --set <seccion.clave=valor>, --log-file <archivo>, --output-format <formato> (también
+// con =), --verbose y --quiet. Devuelve el resto de argumentos.
+func globalArgs(args []string, g *globalOptions) []string {
+	res := []string{args[0]}
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case (a == "--config" || a == "--set" || a == "--log-file" || a == "--output-format") && i+1 < len(args):
+			switch a {
+			case "--config":
+				g.load.File = args[i+1]
+			case "--set":
+				g.load.Overrides = append(g.load.Overrides, args[i+1])
+			case "--log-file":
+				g.logFile = args[i+1]
+			default:
+				g.format = args[i+1]
+			}
+			i++
+		case strings.HasPrefix(a, "--config="):
+			g.load.File = strings.TrimPrefix(a, "--config=")
+		case strings.HasPrefix(a, "--set="):
+			g.load.Overrides = append(g.load.Overrides, strings.TrimPrefix(a, "--set="))
+		case strings.HasPrefix(a, "--log-file="):
+			g.logFile = strings.TrimPrefix(a, "--log-file=")
+		case strings.HasPrefix(a, "--output-format="):
+			g.format = strings.TrimPrefix(a, "--output-format=")
+		case a == "--verbose":
+			g.verbose = true
+		case a == "--quiet":
+			g.quiet = true
+		default:
+			res = append(res, a)
+		}
 	}
+	return res
+}
 
-	red := "\033[31m"
-	reset := "\033[0m"
-	fmt.Printf("%sNo se especificó ninguna acción.%s\n", red, reset)
-	fmt.Printf("Use -h o --help para ver las opciones.\n")
-	os.Exit(1)
+// setupLogging aplica --verbose, --quiet, --log-file y --output-format
+func setupLogging(g globalOptions) error {
+	switch g.format {
+	case "", "text":
+	case "json":
+		logging.SetJSON(true)
+	default:
+		return fmt.Errorf(i18n.T("--output-format: formato desconocido %q (text o json)"), g.format)
+	}
+	switch {
+	case g.verbose && g.quiet:
+		return errors.New(i18n.T("--verbose y --quiet no se pueden usar juntos"))
+	case g.verbose:
+		logging.SetLevel(logging.LevelDebug)
+	case g.quiet:
+		logging.SetLevel(logging.LevelError)
+	}
+	if g.logFile != "" {
+		if err := logging.SetFile(g.logFile); err != nil {
+			return fmt.Errorf("--log-file: %w", err)
+		}
+	}
+	return nil
 }
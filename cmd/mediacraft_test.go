@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLegacyArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		cmd  string
+		rest []string
+		ok   bool
+	}{
+		{[]string{"-c", "a b.mkv"}, "convert", []string{"a b.mkv"}, true},
+		{[]string{"--convert=a.mkv", "--output", "salidas/"}, "convert", []string{"a.mkv", "--output", "salidas/"}, true},
+		// Junto a -c, -o es la salida
+		{[]string{"-c", "a.mkv", "-o", "salidas/"}, "convert", []string{"a.mkv", "--output", "salidas/"}, true},
+		{[]string{"-o", "salidas/", "-c", "a.mkv", "-n"}, "convert", []string{"a.mkv", "-n", "--output", "salidas/"}, true},
+		{[]string{"-o", "Serie"}, "order", []string{"Serie"}, true},
+		{[]string{"-o=Serie", "--dry-run"}, "order", []string{"Serie", "--dry-run"}, true},
+		// Con --convert y --order gana --convert
+		{[]string{"--order", "Serie", "--convert", "a.mkv"}, "convert", []string{"a.mkv"}, true},
+		// No son formas antiguas
+		{[]string{"convert", "a.mkv"}, "", nil, false},
+		{[]string{"--output", "x.mp4"}, "", nil, false},
+		{[]string{}, "", nil, false},
+		// Falta el valor
+		{[]string{"-c"}, "", nil, false},
+		{[]string{"a.mkv", "--order"}, "", nil, false},
+	}
+	for _, tt := range tests {
+		cmd, rest, ok := legacyArgs(tt.args)
+		if cmd != tt.cmd || !reflect.DeepEqual(rest, tt.rest) || ok != tt.ok {
+			t.Errorf("legacyArgs(%q) = %q, %q, %v; se esperaba %q, %q, %v", tt.args, cmd, rest, ok, tt.cmd, tt.rest, tt.ok)
+		}
+	}
+}
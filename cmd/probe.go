@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"mediacraft/config"
+	"mediacraft/encode"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/probe"
+	"mediacraft/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeResult es el análisis de un archivo y el perfil que se le aplicaría
+type probeResult struct {
+	*probe.Info
+	Path    string `json:"path"`
+	Profile string `json:"profile,omitempty"`
+	Reason  string `json:"profile_reason,omitempty"` // Regla, .mediacraft.conf o default_profile
+	Rule    string `json:"rule,omitempty"`           // Nombre de la regla que coincide
+	Error   string `json:"error,omitempty"`
+}
+
+// runProbe implementa "mediacraft probe": resume contenedor, pistas y perfil de cada archivo
+func runProbe(cfg *config.Config, args []string) error {
+	flags := newFlagSet("probe", "<archivo|carpeta>...", "Muestra contenedor, duración, tamaño, pistas y el perfil que se aplicaría a cada archivo")
+	format := flags.String("format", "f", "formato", "table", "Formato de salida: table o json")
+	paths, err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New(i18n.T("falta el archivo o la carpeta a analizar (use mediacraft probe -h)"))
+	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf(i18n.T("formato desconocido: %s"), *format)
+	}
+	files, err := probeFiles(paths)
+	if err != nil {
+		return err
+	}
+	results := make([]probeResult, len(files))
+	failed := 0
+	for i, f := range files {
+		results[i] = probeFile(cfg, f)
+		if results[i].Error != "" {
+			failed++
+		}
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(logging.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for i, r := range results {
+			if i > 0 {
+				fmt.Fprintln(logging.Stdout)
+			}
+			writeProbeTable(logging.Stdout, r)
+		}
+	}
+	if failed > 0 {
+		return errReported
+	}
+	return nil
+}
+
+// probeFiles sustituye cada carpeta por los vídeos que contiene
+func probeFiles(paths []string) ([]string, error) {
+	var res []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.IsDir() {
+			res = append(res, p)
+			continue
+		}
+		n := len(res)
+		err = filepath.WalkDir(p, func(f string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && utils.IsVideoFile(f) {
+				res = append(res, f)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == n {
+			log.Warnf("%s: no hay vídeos", p)
+		}
+	}
+	if len(res) == 0 {
+		return nil, errors.New(i18n.T("no hay nada que analizar"))
+	}
+	return res, nil
+}
+
+// probeFile analiza el archivo y decide su perfil como lo haría convert sin --profile:
+// .mediacraft.conf de la carpeta, reglas de [reglas] o default_profile
+func probeFile(cfg *config.Config, path string) probeResult {
+	r := probeResult{Path: path}
+	info, err := probe.Probe(path)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Info = info
+	if dirProfile, file := config.DirectoryProfile(filepath.Dir(path)); dirProfile != "" && cfg.HasProfile(dirProfile) {
+		r.Profile, r.Reason = dirProfile, i18n.T("fijado por ")+file
+		return r
+	}
+	if rule, ok := cfg.MatchRule(encode.MediaFacts(*info, path, info.Format.Size)); ok {
+		r.Profile, r.Rule = rule.Profile, rule.Name
+		r.Reason = fmt.Sprintf(i18n.T("regla %s: %s"), rule.Name, rule.When())
+		return r
+	}
+	switch {
+	case cfg.RulesDefault != "":
+		r.Profile, r.Reason = cfg.RulesDefault, i18n.T("ninguna regla coincide; defecto de [reglas]")
+	case len(cfg.Rules) > 0:
+		r.Profile, r.Reason = cfg.DefaultProfile, i18n.T("ninguna regla coincide; default_profile")
+	default:
+		r.Profile, r.Reason = cfg.DefaultProfile, "default_profile"
+	}
+	return r
+}
+
+// Nombres de los tipos de pista
+var streamTypeNames = map[string]string{
+	"video": "vídeo", "audio": "audio", "subtitle": "subtítulo", "attachment": "adjunto", "data": "datos",
+}
+
+// writeProbeTable muestra el resultado de un archivo de forma legible
+func writeProbeTable(w io.Writer, r probeResult) {
+	fmt.Fprintf(w, "\033[36m%s\033[0m\n", r.Path)
+	if r.Error != "" {
+		fmt.Fprintf(w, "  \033[31m[ERROR] %s\033[0m\n", r.Error)
+		return
+	}
+	f := r.Info.Format
+	container := []string{f.LongName, formatSeconds(f.Duration), utils.FormatSize(f.Size)}
+	if f.BitRate > 0 {
+		container = append(container, formatBitrate(f.BitRate))
+	}
+	switch n := len(r.Info.Chapters); {
+	case n == 1:
+		container = append(container, i18n.T("1 capítulo"))
+	case n > 1:
+		container = append(container, fmt.Sprintf(i18n.T("%d capítulos"), n))
+	}
+	fmt.Fprintf(w, "  %-11s %s\n", i18n.T("Contenedor"), strings.Join(container, " · "))
+	fmt.Fprintf(w, "  %-11s %s (%s)\n", i18n.T("Perfil"), r.Profile, r.Reason)
+	fmt.Fprintf(w, "  %-3s %-10s %-10s %-7s %s\n", "#", i18n.T("TIPO"), i18n.T("CÓDEC"), i18n.T("IDIOMA"), i18n.T("DETALLE"))
+	for _, s := range r.Info.Streams {
+		kind := i18n.T(streamTypeNames[s.CodecType])
+		if kind == "" {
+			kind = s.CodecType
+		}
+		line := fmt.Sprintf("  %-3d %-10s %-10s %-7s %s", s.Index, kind, s.CodecName, s.Language, streamDetail(s))
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
+// streamDetail resume lo relevante de cada tipo de pista
+func streamDetail(s probe.Stream) string {
+	var d []string
+	switch s.CodecType {
+	case "video":
+		if s.IsCover() {
+			d = append(d, i18n.T("portada"))
+			break
+		}
+		if s.Width > 0 {
+			d = append(d, fmt.Sprintf("%dx%d", s.Width, s.Height))
+		}
+		if s.FrameRate > 0 {
+			d = append(d, strconv.FormatFloat(math.Round(s.FrameRate*1000)/1000, 'f', -1, 64)+" fps")
+		}
+		if s.HDR != "" {
+			d = append(d, s.HDR)
+		}
+		if s.PixFmt != "" {
+			d = append(d, s.PixFmt)
+		}
+	case "audio":
+		switch {
+		case s.ChannelLayout != "":
+			d = append(d, s.ChannelLayout)
+		case s.Channels > 0:
+			d = append(d, fmt.Sprintf(i18n.T("%d canales"), s.Channels))
+		}
+		if s.SampleRate > 0 {
+			d = append(d, fmt.Sprintf("%d Hz", s.SampleRate))
+		}
+	}
+	if s.BitRate > 0 {
+		d = append(d, formatBitrate(s.BitRate))
+	}
+	if s.Title != "" {
+		d = append(d, fmt.Sprintf("%q", s.Title))
+	}
+	if s.Disposition["default"] != 0 {
+		d = append(d, i18n.T("(por defecto)"))
+	}
+	if s.Disposition["forced"] != 0 {
+		d = append(d, i18n.T("(forzado)"))
+	}
+	return strings.Join(d, " ")
+}
+
+// formatSeconds muestra una duración en segundos como 1h30m0s
+func formatSeconds(s float64) string {
+	if s <= 0 {
+		return i18n.T("duración desconocida")
+	}
+	return (time.Duration(s * float64(time.Second))).Round(time.Second).String()
+}
+
+// formatBitrate muestra una tasa de bits en kb/s o Mb/s
+func formatBitrate(b float64) string {
+	if b >= 1000000 {
+		return fmt.Sprintf("%.1f Mb/s", b/1000000)
+	}
+	return fmt.Sprintf("%.0f kb/s", b/1000)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/encode"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"sort"
+	"strings"
+)
+
+// runProfiles implementa "mediacraft profiles": list, show, validate y diff
+func runProfiles(cfg *config.Config, args []string) error {
+	fs := newFlagSet("profiles", "[list | show <perfil> | validate [perfil...] | diff <a> <b>]",
+		"Lista los perfiles, muestra sus comandos de ffmpeg, los valida contra el ffmpeg instalado o los compara")
+	args, err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	action := "list"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	switch action {
+	case "list":
+		return profilesList(cfg)
+	case "show":
+		if len(args) != 1 {
+			return errors.New(i18n.T("uso: mediacraft profiles show <perfil>"))
+		}
+		return profilesShow(cfg, args[0])
+	case "validate":
+		return profilesValidate(cfg, args)
+	case "diff":
+		if len(args) != 2 {
+			return errors.New(i18n.T("uso: mediacraft profiles diff <perfil> <perfil>"))
+		}
+		return profilesDiff(cfg, args[0], args[1])
+	default:
+		return fmt.Errorf(i18n.T("acción desconocida: %s (list, show, validate o diff)"), action)
+	}
+}
+
+// profilesList muestra una línea por perfil; el perfil por defecto se marca con *
+func profilesList(cfg *config.Config) error {
+	fmt.Fprintf(logging.Stdout, "  %-12s  %-6s  %-12s  %-10s  %-10s  %s\n", i18n.T("PERFIL"), "EXT", "VIDEO", "AUDIO", "TARGET", i18n.T("NOTAS"))
+	for _, name := range cfg.ProfileNames() {
+		r, err := encode.ResolveProfile(cfg, name)
+		if err != nil {
+			return err
+		}
+		opts := r.OutputOptions()
+		mark := " "
+		if name == cfg.DefaultProfile {
+			mark = "*"
+		}
+		video, audio := opts["-c:v"], opts["-c:a"]
+		if video == "" && hasArg(r.Final(), "-vn") {
+			video = "none"
+		}
+		if audio == "" && hasArg(r.Final(), "-an") {
+			audio = "none"
+		}
+		var notes []string
+		if len(r.Passes) > 1 {
+			notes = append(notes, fmt.Sprintf(i18n.T("%d pasadas"), len(r.Passes)))
+		}
+		if len(r.Extends) > 0 {
+			notes = append(notes, i18n.T("hereda de ")+strings.Join(r.Extends, " → "))
+		}
+		if len(r.Includes) > 0 {
+			notes = append(notes, i18n.T("incluye ")+strings.Join(r.Includes, ", "))
+		}
+		fmt.Fprintf(logging.Stdout, "%s %-12s  %-6s  %-12s  %-10s  %-10s  %s\n", mark, name, strings.TrimPrefix(r.Ext, "."), video, audio, r.Target, strings.Join(notes, "; "))
+	}
+	fmt.Fprintf(logging.Stdout, i18n.T("\nConfiguración: %s\n"), strings.Join(cfg.Sources, ", "))
+	return nil
+}
+
+// profilesShow muestra los argumentos de ffmpeg completos de un perfil
+func profilesShow(cfg *config.Config, name string) error {
+	r, err := encode.ResolveProfile(cfg, name)
+	if err != nil {
+		return err
+	}
+	cyan := "\033[36m"
+	yellow := "\033[33m"
+	reset := "\033[0m"
+	fmt.Fprintf(logging.Stdout, i18n.T("%sPerfil %s%s\n"), cyan, name, reset)
+	fmt.Fprintf(logging.Stdout, i18n.T("  Contenedor: %s (-f %s)\n"), r.Ext, r.Format)
+	if r.Target != "" {
+		fmt.Fprintf(logging.Stdout, i18n.T("  Destino:    %s\n"), r.Target)
+	}
+	if len(r.Extends) > 0 {
+		fmt.Fprintf(logging.Stdout, i18n.T("  Hereda de:  %s\n"), strings.Join(r.Extends, " → "))
+	}
+	if len(r.Includes) > 0 {
+		fmt.Fprintf(logging.Stdout, i18n.T("  Incluye:    %s\n"), strings.Join(r.Includes, ", "))
+	}
+	if r.Fallback {
+		fmt.Fprintf(logging.Stdout, i18n.T("  %sSin GPU NVIDIA: se usan codificadores por software%s\n"), yellow, reset)
+	}
+	if len(r.Options) > 0 {
+		keys := make([]string, 0, len(r.Options))
+		for k := range r.Options {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprint(logging.Stdout, i18n.T("  Opciones de MediaCraft:\n"))
+		for _, k := range keys {
+			fmt.Fprintf(logging.Stdout, "    %s = %s\n", k, cfg.Redact(r.Options[k]))
+		}
+	}
+	for i, args := range r.Passes {
+		fmt.Fprintf(logging.Stdout, i18n.T("  Pasada %d:\n    ffmpeg %s\n"), i+1, cfg.Redact(encode.QuoteArgs(args)))
+	}
+	return nil
+}
+
+// profilesValidate comprueba los perfiles (todos o los indicados) contra el ffmpeg instalado
+func profilesValidate(cfg *config.Config, names []string) error {
+	caps, err := encode.LoadCapabilities()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		names = cfg.ProfileNames()
+	}
+	green := "\033[32m"
+	red := "\033[31m"
+	reset := "\033[0m"
+	failed := 0
+	for _, name := range names {
+		r, err := encode.ResolveProfile(cfg, name)
+		if err != nil {
+			return err
+		}
+		problems := r.Validate(caps)
+		if len(problems) == 0 {
+			fmt.Fprintf(logging.Stdout, "%s  OK     %s%s\n", green, name, reset)
+			continue
+		}
+		failed++
+		fmt.Fprintf(logging.Stdout, "%s  ERROR  %s%s\n", red, name, reset)
+		for _, p := range problems {
+			fmt.Fprintf(logging.Stdout, "         - %s\n", p)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf(i18n.T("%d de %d perfiles no son válidos con el ffmpeg instalado"), failed, len(names))
+	}
+	return nil
+}
+
+// profilesDiff compara dos perfiles opción a opción
+func profilesDiff(cfg *config.Config, a, b string) error {
+	ra, err := encode.ResolveProfile(cfg, a)
+	if err != nil {
+		return err
+	}
+	rb, err := encode.ResolveProfile(cfg, b)
+	if err != nil {
+		return err
+	}
+	left, right := diffValues(ra), diffValues(rb)
+	keys := map[string]bool{}
+	for k := range left {
+		keys[k] = true
+	}
+	for k := range right {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	red := "\033[31m"
+	green := "\033[32m"
+	reset := "\033[0m"
+	fmt.Fprintf(logging.Stdout, "--- %s\n+++ %s\n", a, b)
+	same := true
+	for _, k := range sorted {
+		l, lok := left[k]
+		r, rok := right[k]
+		if lok && rok && l == r {
+			continue
+		}
+		same = false
+		if lok {
+			fmt.Fprintf(logging.Stdout, "%s- %-20s %s%s\n", red, k, cfg.Redact(l), reset)
+		}
+		if rok {
+			fmt.Fprintf(logging.Stdout, "%s+ %-20s %s%s\n", green, k, cfg.Redact(r), reset)
+		}
+	}
+	if same {
+		fmt.Fprint(logging.Stdout, i18n.T("Los perfiles son equivalentes\n"))
+	}
+	return nil
+}
+
+// diffValues aplana un perfil resuelto en pares clave → valor comparables
+func diffValues(r encode.Resolved) map[string]string {
+	v := map[string]string{"ext": r.Ext, "pasadas": fmt.Sprint(len(r.Passes))}
+	if r.Target != "" {
+		v["target"] = r.Target
+	}
+	for k, o := range r.Options {
+		v[k] = o
+	}
+	final := r.Final()
+	for k, o := range r.OutputOptions() {
+		v[k] = o
+	}
+	for _, flag := range []string{"-vn", "-an", "-sn"} {
+		if hasArg(final, flag) {
+			v[flag] = "sí"
+		}
+	}
+	for i := 0; i+1 < len(final) && final[i] != "-i"; i++ {
+		if strings.HasPrefix(final[i], "-") && final[i] != "-y" {
+			v["entrada "+final[i]] = final[i+1]
+			i++
+		}
+	}
+	return v
+}
+
+func hasArg(args []string, flag string) bool {
+	for _, a := range args {
+		if a == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,119 +1,272 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"os/user"
-	"path"
+	"mediacraft/i18n"
+	"mediacraft/platform"
+	"sort"
 	"strings"
 
 	"gopkg.in/ini.v1"
 )
 
-// Variables globales exportadas
-var (
-	Profiles            map[string][]string
-	ProfileExts         map[string]string
+// Config es la configuración completa de MediaCraft. Se obtiene con Load y se pasa
+// explícitamente a encode, order, watch, jobs y server; no se modifica tras cargarla,
+// así que se puede compartir entre goroutines.
+type Config struct {
+	Profiles            map[string]Profile
 	DefaultProfile      string
 	OutputDir           string
+	OutputName          string
+	CollisionPolicy     string
 	EnableNotifications bool
+	EnableHistory       bool
+	LogDir              string // log_dir: carpeta de los registros de cada trabajo, "salida" o "no"
+	Language            string // idioma: es o en; vacío usa LANG
 	TelegramToken       string
 	TelegramChatID      string
-)
+	Watch               WatchConfig
+	Server              ServerConfig
+	Rules               []Rule            // Reglas de [reglas] para elegir el perfil, en orden
+	Tools               map[string]string // Rutas de [herramientas] (ffmpeg, ffprobe, 7z)
+	RulesDefault        string            // Perfil si no coincide ninguna regla; vacío usa DefaultProfile
+	Sources             []string          // Capas cargadas, de menor a mayor prioridad
+	Warnings            []string          // Avisos de la carga (p.ej. secretos legibles por todos)
 
-// LoadProfiles carga perfiles y configuración desde el archivo INI
-func LoadProfiles() error {
-	var confPath string
-	if os.PathSeparator == '\\' { // Windows
-		userProfile := os.Getenv("USERPROFILE")
-		confPath = path.Join(userProfile, ".config", "mediacraft", "mediacraft.conf")
-	} else { // Unix-like
-		usr, err := user.Current()
-		if err != nil {
-			return err
-		}
-		confPath = path.Join(usr.HomeDir, ".config", "mediacraft", "mediacraft.conf")
+	secrets []string // Valores secretos, para Redact
+}
+
+// WatchConfig es la sección [watch] (mediacraft watch)
+type WatchConfig struct {
+	Action    string
+	Profile   string
+	Interval  int
+	Stable    int
+	DoneDir   string
+	FailedDir string
+	SeriesDir string
+}
+
+// ServerConfig es la sección [servidor] (mediacraft serve)
+type ServerConfig struct {
+	Listen  string
+	Workers int
+	Token   string
+}
+
+// LoadOptions indica de dónde cargar la configuración además de las capas habituales
+type LoadOptions struct {
+	File      string   // Archivo de usuario (--config); vacío usa MEDIACRAFT_CONFIG o el de siempre
+	Overrides []string // Valores seccion.clave=valor (--set)
+	WorkDir   string   // Carpeta del .mediacraft.conf de proyecto; vacío usa la actual
+}
+
+// Default devuelve la configuración por defecto, sin perfiles
+func Default() *Config {
+	return &Config{
+		Profiles:        map[string]Profile{},
+		DefaultProfile:  "telegram",
+		OutputName:      "{name}.{ext}",
+		CollisionPolicy: "suffix",
+		EnableHistory:   true,
+		Watch: WatchConfig{
+			Action: "convert", Interval: 10, Stable: 30,
+			DoneDir: "done", FailedDir: "failed", SeriesDir: "series",
+		},
+		Server: ServerConfig{Listen: "127.0.0.1:8080", Workers: 1},
+	}
+}
+
+// Load carga perfiles y configuración combinando todas las capas (ver loadLayers)
+func Load(opts LoadOptions) (*Config, error) {
+	file, sources, warnings, err := loadLayers(opts)
+	if err != nil {
+		return nil, err
 	}
-	if _, err := os.Stat(confPath); err != nil {
-		return fmt.Errorf("no se encontró el archivo de configuración: %s", confPath)
+	c := Default()
+	c.Sources = sources
+	c.Warnings = warnings
+	if err := c.parse(file); err != nil {
+		return nil, err
 	}
-	cfg, err := ini.Load(confPath)
+	return c, nil
+}
+
+// Language devuelve el idioma de los mensajes indicado en las capas ([mediacraft]
+// idioma), o vacío si no se indica o no se pueden leer. Se consulta antes de Load para
+// que sus errores salgan ya en ese idioma.
+func Language(opts LoadOptions) string {
+	file, _, _, err := loadLayers(opts)
 	if err != nil {
-		return err
+		return ""
 	}
-	Profiles = map[string][]string{}
-	ProfileExts = map[string]string{}
-	DefaultProfile = "telegram"
-	OutputDir = ""
-	EnableNotifications = false
-	TelegramToken = ""
-	TelegramChatID = ""
+	return strings.ToLower(strings.TrimSpace(file.Section("mediacraft").Key("idioma").String()))
+}
+
+// parse lee las secciones generales y compila los perfiles
+func (c *Config) parse(cfg *ini.File) error {
 	// Leer configuración general
 	if sec, err := cfg.GetSection("mediacraft"); err == nil {
 		if sec.HasKey("default_profile") {
-			DefaultProfile = sec.Key("default_profile").String()
+			c.DefaultProfile = sec.Key("default_profile").String()
 		}
 		if sec.HasKey("output_dir") {
-			OutputDir = sec.Key("output_dir").String()
+			c.OutputDir = sec.Key("output_dir").String()
+		}
+		if sec.HasKey("output_name") {
+			c.OutputName = sec.Key("output_name").String()
+		}
+		if sec.HasKey("colision") {
+			c.CollisionPolicy = strings.ToLower(strings.TrimSpace(sec.Key("colision").String()))
+			switch c.CollisionPolicy {
+			case "skip", "overwrite", "suffix":
+			default:
+				return fmt.Errorf(i18n.T("[mediacraft] colision: valor no válido %q (skip, overwrite o suffix)"), c.CollisionPolicy)
+			}
 		}
 		if sec.HasKey("notificaciones") {
-			v := sec.Key("notificaciones").String()
-			if v == "1" || v == "true" || v == "TRUE" || v == "True" {
-				EnableNotifications = true
+			c.EnableNotifications = IsTrue(sec.Key("notificaciones").String())
+		}
+		if sec.HasKey("historial") {
+			c.EnableHistory = IsTrue(sec.Key("historial").String())
+		}
+		if sec.HasKey("log_dir") {
+			c.LogDir = strings.TrimSpace(sec.Key("log_dir").String())
+		}
+		if sec.HasKey("idioma") {
+			c.Language = strings.ToLower(strings.TrimSpace(sec.Key("idioma").String()))
+			switch c.Language {
+			case "", "es", "en":
+			default:
+				return fmt.Errorf(i18n.T("[mediacraft] idioma: valor no válido %q (es o en)"), c.Language)
 			}
 		}
 	}
 	// Leer configuración de Telegram
 	if sec, err := cfg.GetSection("telegram"); err == nil {
 		if sec.HasKey("token") {
-			TelegramToken = sec.Key("token").String()
+			if c.TelegramToken, err = c.secret("telegram", "token", sec.Key("token").String()); err != nil {
+				return err
+			}
 		}
 		if sec.HasKey("chat_id") {
-			TelegramChatID = sec.Key("chat_id").String()
+			c.TelegramChatID = sec.Key("chat_id").String()
 		}
 	}
-	for _, section := range cfg.Sections() {
-		name := section.Name()
-		if len(name) > 9 && name[:9] == "perfiles." {
-			profileName := name[9:]
-			args := []string{}
-			var videoCodec, audioCodec, kvideo, kaudio string
-			for _, key := range section.KeyStrings() {
-				v := section.Key(key).String()
-				if v == "" {
-					continue
-				}
-				k := strings.ToLower(strings.TrimSpace(key))
-				switch k {
-				case "ext":
-					ProfileExts[profileName] = v
-				case "video":
-					videoCodec = strings.TrimSpace(v)
-				case "audio":
-					audioCodec = strings.TrimSpace(v)
-				case "kvideo":
-					kvideo = strings.TrimSpace(v)
-				case "kaudio":
-					kaudio = strings.TrimSpace(v)
-				default:
-					vNorm := strings.TrimSpace(strings.ReplaceAll(v, "=", ""))
-					args = append(args, "-"+k, vNorm)
-				}
+	// Leer configuración del modo vigilancia
+	w := &c.Watch
+	if sec, err := cfg.GetSection("watch"); err == nil {
+		if sec.HasKey("accion") {
+			w.Action = strings.ToLower(strings.TrimSpace(sec.Key("accion").String()))
+			switch w.Action {
+			case "convert", "order", "convert_order":
+			default:
+				return fmt.Errorf(i18n.T("[watch] accion: valor no válido %q (convert, order o convert_order)"), w.Action)
 			}
-			if videoCodec != "" {
-				args = append(args, "-c:v", videoCodec)
+		}
+		if sec.HasKey("perfil") {
+			w.Profile = strings.TrimSpace(sec.Key("perfil").String())
+		}
+		if sec.HasKey("intervalo") {
+			if w.Interval, err = sec.Key("intervalo").Int(); err != nil || w.Interval <= 0 {
+				return errors.New(i18n.T("[watch] intervalo: se esperaba un número de segundos positivo"))
 			}
-			if kvideo != "" {
-				args = append(args, "-b:v", kvideo)
+		}
+		if sec.HasKey("estable") {
+			if w.Stable, err = sec.Key("estable").Int(); err != nil || w.Stable < 0 {
+				return errors.New(i18n.T("[watch] estable: se esperaba un número de segundos"))
 			}
-			if audioCodec != "" {
-				args = append(args, "-c:a", audioCodec)
+		}
+		if sec.HasKey("done_dir") {
+			w.DoneDir = sec.Key("done_dir").String()
+		}
+		if sec.HasKey("failed_dir") {
+			w.FailedDir = sec.Key("failed_dir").String()
+		}
+		if sec.HasKey("series_dir") {
+			w.SeriesDir = sec.Key("series_dir").String()
+		}
+	}
+	// Leer configuración del servidor HTTP
+	if sec, err := cfg.GetSection("servidor"); err == nil {
+		if sec.HasKey("escucha") {
+			c.Server.Listen = strings.TrimSpace(sec.Key("escucha").String())
+		}
+		if sec.HasKey("workers") {
+			if c.Server.Workers, err = sec.Key("workers").Int(); err != nil || c.Server.Workers <= 0 {
+				return errors.New(i18n.T("[servidor] workers: se esperaba un número positivo"))
 			}
-			if kaudio != "" {
-				args = append(args, "-b:a", kaudio)
+		}
+		if sec.HasKey("token") {
+			if c.Server.Token, err = c.secret("servidor", "token", sec.Key("token").String()); err != nil {
+				return err
 			}
-			Profiles[profileName] = args
 		}
 	}
-	return nil
+	// Rutas de las herramientas externas (un ejecutable o su carpeta)
+	if sec, err := cfg.GetSection("herramientas"); err == nil {
+		c.Tools = map[string]string{}
+		for _, key := range sec.Keys() {
+			if !platform.IsTool(key.Name()) {
+				return fmt.Errorf(i18n.T("[herramientas] %s: herramienta desconocida (%s)"), key.Name(), strings.Join(platform.ToolNames(), ", "))
+			}
+			if v := strings.TrimSpace(key.String()); v != "" {
+				c.Tools[key.Name()] = v
+			}
+		}
+	}
+	// Compilar los perfiles; se informa de todos los errores a la vez
+	var errs []error
+	for _, section := range cfg.Sections() {
+		name := section.Name()
+		if !strings.HasPrefix(name, "perfiles.") || len(name) == len("perfiles.") {
+			continue
+		}
+		p, err := compileProfile(cfg, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.Profiles[p.Name] = p
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	// Las reglas se leen al final porque se comprueba que existan sus perfiles
+	return c.parseRules(cfg)
+}
+
+// HasProfile indica si existe el perfil
+func (c *Config) HasProfile(name string) bool {
+	_, ok := c.Profiles[name]
+	return ok
+}
+
+// ProfileNames devuelve los nombres de los perfiles, ordenados
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for n := range c.Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileOption devuelve una opción propia de MediaCraft (no de ffmpeg) de un perfil,
+// o def si no está definida
+func (c *Config) ProfileOption(profile, key, def string) string {
+	if v := c.Profiles[profile].Options[key]; v != "" {
+		return v
+	}
+	return def
+}
+
+// IsTrue interpreta los valores booleanos habituales del archivo de configuración
+func IsTrue(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "si", "sí", "yes", "on":
+		return true
+	}
+	return false
 }
@@ -0,0 +1,497 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mediacraft/i18n"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v3"
+)
+
+// Formatos de archivo de configuración admitidos
+const (
+	FormatINI  = "ini"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
+
+// Extensiones que se buscan en cada carpeta de configuración, por orden de preferencia
+var confExts = []string{".conf", ".yaml", ".yml", ".toml", ".json"}
+
+// Claves cuyas listas se unen con comas; el resto se unen como argumentos de ffmpeg
+var commaListKeys = map[string]bool{"include": true}
+
+// FormatOf devuelve el formato de un archivo según su extensión; INI si no es otro
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".json":
+		return FormatJSON
+	}
+	return FormatINI
+}
+
+// findConf devuelve el primer archivo base.conf, base.yaml, base.yml, base.toml o
+// base.json que existe en dir, o vacío si no hay ninguno
+func findConf(dir, base string) string {
+	for _, ext := range confExts {
+		p := filepath.Join(dir, base+ext)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// readFile lee un archivo de configuración en cualquiera de los formatos admitidos.
+// YAML, TOML y JSON se traducen al mismo modelo que el INI: cada objeto anidado es
+// una sección (perfiles: {movil: {...}} es [perfiles.movil]) y las claves sueltas
+// del primer nivel van a la sección por defecto.
+func readFile(path string) (*ini.File, error) {
+	format := FormatOf(path)
+	if format == FormatINI {
+		return ini.Load(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := ini.Empty()
+	set := func(section, key, value string) {
+		if section == "" {
+			section = ini.DefaultSection
+		}
+		cfg.Section(section).Key(key).SetValue(value)
+	}
+	switch format {
+	case FormatYAML:
+		err = decodeYAML(data, set)
+	case FormatTOML:
+		err = decodeTOML(data, set)
+	case FormatJSON:
+		err = decodeJSON(data, set)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// mergeFile copia encima de dst las claves de src; las que ya existían se sobrescriben
+func mergeFile(dst, src *ini.File) {
+	for _, sec := range src.Sections() {
+		d := dst.Section(sec.Name())
+		for _, k := range sec.Keys() {
+			d.Key(k.Name()).SetValue(k.Value())
+		}
+	}
+}
+
+// joinList convierte una lista en el valor de una clave: include se une con comas y
+// el resto como argumentos (entrecomillados si tienen espacios, ver splitArgs)
+func joinList(key string, items []string) string {
+	if commaListKeys[strings.ToLower(key)] {
+		return strings.Join(items, ", ")
+	}
+	parts := make([]string, len(items))
+	for i, it := range items {
+		if it == "" || strings.ContainsAny(it, " \t") {
+			it = `"` + it + `"`
+		}
+		parts[i] = it
+	}
+	return strings.Join(parts, " ")
+}
+
+// joinSection añade un nivel al nombre de una sección
+func joinSection(section, name string) string {
+	if section == "" {
+		return name
+	}
+	return section + "." + name
+}
+
+// decodeYAML recorre el documento como nodos para conservar el orden de las claves
+// y el texto literal de los valores (0900 sigue siendo 0900)
+func decodeYAML(data []byte, set func(section, key, value string)) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return errors.New(i18n.T("se esperaba un objeto en el primer nivel"))
+	}
+	var walk func(section string, n *yaml.Node) error
+	walk = func(section string, n *yaml.Node) error {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i].Value, n.Content[i+1]
+			if val.Kind == yaml.AliasNode {
+				val = val.Alias
+			}
+			switch val.Kind {
+			case yaml.MappingNode:
+				if err := walk(joinSection(section, key), val); err != nil {
+					return err
+				}
+			case yaml.SequenceNode:
+				var items []string
+				for _, it := range val.Content {
+					if it.Kind != yaml.ScalarNode {
+						return fmt.Errorf(i18n.T("línea %d: %s: las listas sólo pueden tener valores simples"), it.Line, key)
+					}
+					items = append(items, it.Value)
+				}
+				set(section, key, joinList(key, items))
+			default:
+				v := val.Value
+				if val.Tag == "!!null" {
+					v = ""
+				}
+				set(section, key, v)
+			}
+		}
+		return nil
+	}
+	return walk("", root)
+}
+
+// decodeTOML usa el orden de definición de las claves que da el decodificador
+func decodeTOML(data []byte, set func(section, key, value string)) error {
+	var tree map[string]interface{}
+	md, err := toml.Decode(string(data), &tree)
+	if err != nil {
+		return err
+	}
+	for _, k := range md.Keys() {
+		var val interface{} = tree
+		for _, part := range k {
+			m, ok := val.(map[string]interface{})
+			if !ok {
+				val = nil
+				break
+			}
+			val = m[part]
+		}
+		section, key := strings.Join(k[:len(k)-1], "."), k[len(k)-1]
+		switch v := val.(type) {
+		case map[string]interface{}, nil:
+			// Tablas: sus claves vienen después
+		case []map[string]interface{}:
+			return fmt.Errorf(i18n.T("%s: no se admiten listas de tablas"), k)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, it := range v {
+				if _, ok := it.(map[string]interface{}); ok {
+					return fmt.Errorf(i18n.T("%s: las listas sólo pueden tener valores simples"), k)
+				}
+				items[i] = fmt.Sprint(it)
+			}
+			set(section, key, joinList(key, items))
+		default:
+			set(section, key, fmt.Sprint(v))
+		}
+	}
+	return nil
+}
+
+// decodeJSON lee el documento token a token para conservar el orden de las claves
+func decodeJSON(data []byte, set func(section, key, value string)) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return errors.New(i18n.T("se esperaba un objeto en el primer nivel"))
+	}
+	scalar := func(tok json.Token) (string, bool) {
+		switch v := tok.(type) {
+		case string:
+			return v, true
+		case json.Number:
+			return v.String(), true
+		case bool:
+			return strconv.FormatBool(v), true
+		case nil:
+			return "", true
+		}
+		return "", false
+	}
+	var walk func(section string) error
+	walk = func(section string) error {
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := tok.(string)
+			if tok, err = dec.Token(); err != nil {
+				return err
+			}
+			switch tok {
+			case json.Delim('{'):
+				if err := walk(joinSection(section, key)); err != nil {
+					return err
+				}
+			case json.Delim('['):
+				var items []string
+				for dec.More() {
+					it, err := dec.Token()
+					if err != nil {
+						return err
+					}
+					v, ok := scalar(it)
+					if !ok {
+						return fmt.Errorf(i18n.T("%s: las listas sólo pueden tener valores simples"), key)
+					}
+					items = append(items, v)
+				}
+				if _, err := dec.Token(); err != nil { // ]
+					return err
+				}
+				set(section, key, joinList(key, items))
+			default:
+				v, _ := scalar(tok)
+				set(section, key, v)
+			}
+		}
+		_, err := dec.Token() // }
+		return err
+	}
+	if err := walk(""); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New(i18n.T("contenido inesperado tras el objeto principal"))
+	}
+	return nil
+}
+
+// convSection es una sección del archivo a convertir, con su nombre partido en niveles
+type convSection struct {
+	path    []string
+	comment string
+	keys    []*ini.Key
+}
+
+// Convert lee un archivo de configuración en cualquier formato y lo devuelve en el
+// formato indicado. Se conservan el orden de secciones y claves y, en YAML y TOML,
+// los comentarios; los valores se escriben siempre como texto.
+func Convert(path, format string) ([]byte, error) {
+	cfg, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sections []convSection
+	for _, sec := range cfg.Sections() {
+		if len(sec.Keys()) == 0 && sec.Name() == ini.DefaultSection {
+			continue
+		}
+		var p []string
+		if sec.Name() != ini.DefaultSection {
+			// Sólo el primer punto separa niveles: [perfiles.movil] es perfiles → movil
+			p = strings.SplitN(sec.Name(), ".", 2)
+		}
+		sections = append(sections, convSection{path: p, comment: cleanComment(sec.Comment), keys: sec.Keys()})
+	}
+	switch format {
+	case FormatINI:
+		var buf bytes.Buffer
+		_, err := cfg.WriteTo(&buf)
+		return buf.Bytes(), err
+	case FormatYAML:
+		return convertYAML(sections)
+	case FormatTOML:
+		return convertTOML(sections), nil
+	case FormatJSON:
+		return convertJSON(sections)
+	}
+	return nil, fmt.Errorf(i18n.T("formato desconocido: %s (ini, yaml, toml o json)"), format)
+}
+
+// cleanComment quita los ; y # del principio de cada línea de un comentario INI
+func cleanComment(c string) string {
+	if c == "" {
+		return ""
+	}
+	lines := strings.Split(c, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(l), ";#"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// yamlChild devuelve la clave y el objeto hijo key de m, creándolos si no existen
+func yamlChild(m *yaml.Node, key string) (head, child *yaml.Node, created bool) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key && m.Content[i+1].Kind == yaml.MappingNode {
+			return m.Content[i], m.Content[i+1], false
+		}
+	}
+	head = &yaml.Node{Kind: yaml.ScalarNode, Value: key}
+	child = &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, head, child)
+	return head, child, true
+}
+
+func convertYAML(sections []convSection) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range sections {
+		target := root
+		comment := s.comment
+		for _, part := range s.path {
+			var head *yaml.Node
+			var created bool
+			head, target, created = yamlChild(target, part)
+			// El comentario va en el primer nivel nuevo (fragment: y no nvenc_h264:)
+			if created && comment != "" {
+				head.HeadComment, comment = comment, ""
+			}
+		}
+		for _, k := range s.keys {
+			target.Content = append(target.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: k.Name(), HeadComment: cleanComment(k.Comment)},
+				&yaml.Node{Kind: yaml.ScalarNode, Value: k.Value()})
+		}
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// Claves que TOML admite sin comillas
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(k string) string {
+	if bareKey.MatchString(k) {
+		return k
+	}
+	return tomlString(k)
+}
+
+func tomlString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func tomlComment(buf *bytes.Buffer, c string) {
+	if c == "" {
+		return
+	}
+	for _, l := range strings.Split(c, "\n") {
+		fmt.Fprintf(buf, "# %s\n", l)
+	}
+}
+
+func convertTOML(sections []convSection) []byte {
+	var buf bytes.Buffer
+	for i, s := range sections {
+		if len(s.path) > 0 {
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+			tomlComment(&buf, s.comment)
+			parts := make([]string, len(s.path))
+			for j, p := range s.path {
+				parts[j] = tomlKey(p)
+			}
+			fmt.Fprintf(&buf, "[%s]\n", strings.Join(parts, "."))
+		}
+		for _, k := range s.keys {
+			tomlComment(&buf, cleanComment(k.Comment))
+			fmt.Fprintf(&buf, "%s = %s\n", tomlKey(k.Name()), tomlString(k.Value()))
+		}
+	}
+	return buf.Bytes()
+}
+
+// jsonObject es un objeto JSON que conserva el orden de sus claves
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newJSONObject() *jsonObject {
+	return &jsonObject{values: map[string]interface{}{}}
+}
+
+func (o *jsonObject) set(k string, v interface{}) {
+	if _, ok := o.values[k]; !ok {
+		o.keys = append(o.keys, k)
+	}
+	o.values[k] = v
+}
+
+func (o *jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		vb, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		buf.Write(vb)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func convertJSON(sections []convSection) ([]byte, error) {
+	root := newJSONObject()
+	for _, s := range sections {
+		target := root
+		for _, part := range s.path {
+			next, ok := target.values[part].(*jsonObject)
+			if !ok {
+				next = newJSONObject()
+				target.set(part, next)
+			}
+			target = next
+		}
+		for _, k := range s.keys {
+			target.set(k.Name(), k.Value())
+		}
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// decodeAll pasa data por el decodificador y devuelve las claves como
+// "seccion|clave=valor", en el orden en que se fijan
+func decodeAll(decode func([]byte, func(section, key, value string)) error, data string) ([]string, error) {
+	var got []string
+	err := decode([]byte(data), func(section, key, value string) {
+		got = append(got, section+"|"+key+"="+value)
+	})
+	return got, err
+}
+
+type decodeTest struct {
+	name string
+	data string
+	want []string
+}
+
+// runDecodeTests comprueba las claves que se obtienen de cada caso de valid y que
+// cada archivo de invalid (por nombre) da un error
+func runDecodeTests(t *testing.T, decode func([]byte, func(section, key, value string)) error, valid []decodeTest, invalid map[string]string) {
+	t.Helper()
+	for name, data := range invalid {
+		if got, err := decodeAll(decode, data); err == nil {
+			t.Errorf("%s: se esperaba un error, se obtuvo %q", name, got)
+		}
+	}
+	for _, tt := range valid {
+		got, err := decodeAll(decode, tt.data)
+		if err != nil {
+			t.Errorf("%s: error inesperado: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n obtenido %q\n esperado %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	runDecodeTests(t, decodeYAML, []decodeTest{
+		{name: "vacío", data: "", want: nil},
+		{
+			name: "secciones anidadas en orden",
+			data: "mediacraft:\n  default_profile: movil\n  colision: skip\nperfiles:\n  movil:\n    kvideo: 900k\n    ext: mp4\n",
+			want: []string{"mediacraft|default_profile=movil", "mediacraft|colision=skip", "perfiles.movil|kvideo=900k", "perfiles.movil|ext=mp4"},
+		},
+		{name: "clave suelta", data: "perfil: movil\n", want: []string{"|perfil=movil"}},
+		{name: "texto literal", data: "a:\n  hora: 0900\n  si: yes\n  n: 1.50\n", want: []string{"a|hora=0900", "a|si=yes", "a|n=1.50"}},
+		{name: "nulo", data: "a:\n  x: ~\n  y: null\n  z:\n", want: []string{"a|x=", "a|y=", "a|z="}},
+		{
+			name: "listas",
+			data: "perfiles:\n  movil:\n    include: [nvenc_h264, nvenc_slow]\n    opciones_salida: [\"-metadata\", \"title=a b\"]\n",
+			want: []string{"perfiles.movil|include=nvenc_h264, nvenc_slow", `perfiles.movil|opciones_salida=-metadata "title=a b"`},
+		},
+		{name: "ancla y alias", data: "base: &b\n  ext: mp4\nperfiles:\n  movil: *b\n", want: []string{"base|ext=mp4", "perfiles.movil|ext=mp4"}},
+	}, map[string]string{
+		"lista en el primer nivel":   "- a\n- b\n",
+		"escalar en el primer nivel": "hola\n",
+		"lista de objetos":           "a:\n  include:\n    - x: 1\n",
+		"lista de listas":            "a:\n  include: [[x]]\n",
+		"sintaxis":                   "a: [b\n",
+		"sangría":                    "a:\n  b: 1\n c: 2\n",
+	})
+}
+
+func TestDecodeTOML(t *testing.T) {
+	runDecodeTests(t, decodeTOML, []decodeTest{
+		{name: "vacío", data: "", want: nil},
+		{
+			name: "tablas en orden",
+			data: "perfil = \"movil\"\n[mediacraft]\ndefault_profile = \"movil\"\n[perfiles.movil]\nkvideo = \"900k\"\ncrf = 28\n",
+			want: []string{"|perfil=movil", "mediacraft|default_profile=movil", "perfiles.movil|kvideo=900k", "perfiles.movil|crf=28"},
+		},
+		{name: "booleanos", data: "[a]\nremux = false\n", want: []string{"a|remux=false"}},
+		{
+			name: "listas",
+			data: "[perfiles.movil]\ninclude = [\"nvenc_h264\", \"nvenc_slow\"]\nopciones_salida = [\"-metadata\", \"title=a b\"]\n",
+			want: []string{"perfiles.movil|include=nvenc_h264, nvenc_slow", `perfiles.movil|opciones_salida=-metadata "title=a b"`},
+		},
+		{name: "tabla en línea", data: "[perfiles]\nmovil = { ext = \"mp4\" }\n", want: []string{"perfiles.movil|ext=mp4"}},
+	}, map[string]string{
+		"lista de tablas":  "[[perfiles]]\next = \"mp4\"\n",
+		"lista con tablas": "[a]\ninclude = [\"x\", { y = 1 }]\n",
+		"sin comillas":     "[a]\next = mp4\n",
+		"tabla sin cerrar": "[a\next = \"mp4\"\n",
+		"clave repetida":   "[a]\next = \"mp4\"\next = \"mkv\"\n",
+	})
+}
+
+func TestDecodeJSON(t *testing.T) {
+	runDecodeTests(t, decodeJSON, []decodeTest{
+		{name: "objeto vacío", data: "{}", want: nil},
+		{
+			name: "objetos anidados en orden",
+			data: `{"perfil": "movil", "perfiles": {"movil": {"kvideo": "900k", "crf": 28}}, "mediacraft": {"default_profile": "movil"}}`,
+			want: []string{"|perfil=movil", "perfiles.movil|kvideo=900k", "perfiles.movil|crf=28", "mediacraft|default_profile=movil"},
+		},
+		{name: "números literales", data: `{"a": {"n": 1.50, "e": 1e3}}`, want: []string{"a|n=1.50", "a|e=1e3"}},
+		{name: "booleanos y nulo", data: `{"a": {"remux": true, "x": null}}`, want: []string{"a|remux=true", "a|x="}},
+		{
+			name: "listas",
+			data: `{"perfiles": {"movil": {"include": ["nvenc_h264", "nvenc_slow"], "opciones_salida": ["-metadata", "title=a b"], "vacia": []}}}`,
+			want: []string{"perfiles.movil|include=nvenc_h264, nvenc_slow", `perfiles.movil|opciones_salida=-metadata "title=a b"`, "perfiles.movil|vacia="},
+		},
+	}, map[string]string{
+		"vacío":                      "",
+		"lista en el primer nivel":   `["a"]`,
+		"escalar en el primer nivel": `"a"`,
+		"lista de objetos":           `{"a": {"include": [{"x": 1}]}}`,
+		"lista de listas":            `{"a": {"include": [["x"]]}}`,
+		"sin cerrar":                 `{"a": {"b": "c"}`,
+		"contenido tras el objeto":   `{"a": {}} {"b": {}}`,
+		"sintaxis":                   `{"a": }`,
+	})
+}
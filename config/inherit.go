@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"mediacraft/i18n"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// Prefijo de las secciones de fragmentos reutilizables ([fragment.nombre])
+const fragmentPrefix = "fragment."
+
+// keyValue es el valor de una clave y la sección de la que procede
+type keyValue struct {
+	value  string
+	origin string
+}
+
+// rawProfile son las claves de un perfil ya resueltas extends e include, en orden
+type rawProfile struct {
+	keys     []string
+	values   map[string]keyValue
+	extends  []string
+	includes []string
+}
+
+// set fija una clave; las que ya existían conservan su posición
+func (r *rawProfile) set(key string, kv keyValue) {
+	if _, ok := r.values[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.values[key] = kv
+}
+
+// unset elimina una clave heredada
+func (r *rawProfile) unset(key string) {
+	if _, ok := r.values[key]; !ok {
+		return
+	}
+	delete(r.values, key)
+	for i, k := range r.keys {
+		if k == key {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// merge copia encima las claves de otro perfil resuelto
+func (r *rawProfile) merge(o rawProfile) {
+	for _, k := range o.keys {
+		r.set(k, o.values[k])
+	}
+}
+
+// resolveSection devuelve las claves de una sección de perfil o fragmento aplicando,
+// por este orden, el perfil de extends, los fragmentos de include y las claves
+// propias (que tienen prioridad). Una clave propia vacía anula la heredada.
+// visiting es la cadena de secciones en resolución, para detectar ciclos.
+func resolveSection(cfg *ini.File, name string, visiting []string) (rawProfile, error) {
+	for i, v := range visiting {
+		if v == name {
+			chain := append(append([]string{}, visiting[i:]...), name)
+			return rawProfile{}, fmt.Errorf(i18n.T("[%s] herencia circular: %s"), visiting[0], strings.Join(chain, " → "))
+		}
+	}
+	res := rawProfile{values: map[string]keyValue{}}
+	sec, err := cfg.GetSection(name)
+	if err != nil {
+		if len(visiting) == 0 {
+			return res, fmt.Errorf(i18n.T("[%s] no existe la sección"), name)
+		}
+		key := "extends"
+		if strings.HasPrefix(name, fragmentPrefix) {
+			key = "include"
+		}
+		return res, fmt.Errorf(i18n.T("[%s] %s: no existe la sección [%s]"), visiting[len(visiting)-1], key, name)
+	}
+	visiting = append(visiting, name)
+	isFragment := strings.HasPrefix(name, fragmentPrefix)
+
+	// Leer las claves propias (la última aparición de una clave gana)
+	own := rawProfile{values: map[string]keyValue{}}
+	var empty []string
+	var extends string
+	var includes []string
+	for _, key := range sec.KeyStrings() {
+		k := strings.ToLower(strings.TrimSpace(key))
+		v := strings.TrimSpace(sec.Key(key).String())
+		switch {
+		case k == "extends":
+			if isFragment {
+				return res, fmt.Errorf(i18n.T("[%s] extends: sólo se admite en perfiles; use include"), name)
+			}
+			extends = v
+		case k == "include":
+			for _, inc := range strings.Split(v, ",") {
+				if inc = strings.TrimSpace(inc); inc != "" {
+					includes = append(includes, inc)
+				}
+			}
+		case v == "":
+			empty = append(empty, k)
+		default:
+			own.set(k, keyValue{value: v, origin: name})
+		}
+	}
+
+	if extends != "" {
+		parent, err := resolveSection(cfg, "perfiles."+extends, visiting)
+		if err != nil {
+			return res, err
+		}
+		res.merge(parent)
+		res.extends = append([]string{extends}, parent.extends...)
+		res.includes = append(res.includes, parent.includes...)
+	}
+	for _, inc := range includes {
+		frag, err := resolveSection(cfg, fragmentPrefix+inc, visiting)
+		if err != nil {
+			return res, err
+		}
+		res.merge(frag)
+		res.includes = append(res.includes, inc)
+		res.includes = append(res.includes, frag.includes...)
+	}
+	res.merge(own)
+	for _, k := range empty {
+		res.unset(k)
+	}
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+func TestResolveSection(t *testing.T) {
+	f, err := ini.Load([]byte(`[fragment.nvenc]
+video = h264_nvenc
+preset = slow
+
+[fragment.lento]
+include = nvenc
+preset = p7
+
+[perfiles.base]
+ext = mp4
+kvideo = 2M
+vf = scale=1280:-2
+
+[perfiles.x]
+extends = base
+include = lento
+kvideo = 1M
+vf =
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := resolveSection(f, "perfiles.x", nil)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	got := map[string]string{}
+	for _, k := range raw.keys {
+		got[k] = raw.values[k].value + " (" + raw.values[k].origin + ")"
+	}
+	want := map[string]string{
+		"ext":    "mp4 (perfiles.base)",
+		"kvideo": "1M (perfiles.x)",
+		"video":  "h264_nvenc (fragment.nvenc)",
+		"preset": "p7 (fragment.lento)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("claves = %q, se esperaba %q", got, want)
+	}
+	if !reflect.DeepEqual(raw.extends, []string{"base"}) || !reflect.DeepEqual(raw.includes, []string{"lento", "nvenc"}) {
+		t.Errorf("extends = %q, include = %q", raw.extends, raw.includes)
+	}
+}
+
+func TestResolveSectionCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  string
+		chain string // Cadena que debe aparecer en el error
+	}{
+		{"extends de sí mismo", "[perfiles.a]\nextends = a\n", "perfiles.a → perfiles.a"},
+		{"extends en ciclo", "[perfiles.a]\nextends = b\n[perfiles.b]\nextends = c\n[perfiles.c]\nextends = a\n", "perfiles.a → perfiles.b → perfiles.c → perfiles.a"},
+		{"include en ciclo", "[perfiles.a]\ninclude = f\n[fragment.f]\ninclude = g\n[fragment.g]\ninclude = f\n", "fragment.f → fragment.g → fragment.f"},
+		{"fragmento que se incluye a sí mismo", "[perfiles.a]\ninclude = f\n[fragment.f]\ninclude = f\n", "fragment.f → fragment.f"},
+	}
+	for _, tt := range tests {
+		f, err := ini.Load([]byte(tt.conf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = resolveSection(f, "perfiles.a", nil)
+		if err == nil || !strings.Contains(err.Error(), tt.chain) {
+			t.Errorf("%s: error %v, se esperaba la cadena %s", tt.name, err, tt.chain)
+		}
+	}
+}
+
+func TestResolveSectionErrors(t *testing.T) {
+	for _, conf := range []string{
+		"[perfiles.b]\next = mp4\n",                              // No existe el perfil
+		"[perfiles.a]\nextends = b\n",                            // extends a un perfil que no existe
+		"[perfiles.a]\ninclude = f\n",                            // include de un fragmento que no existe
+		"[perfiles.a]\ninclude = f\n[fragment.f]\nextends = a\n", // extends en un fragmento
+	} {
+		f, err := ini.Load([]byte(conf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw, err := resolveSection(f, "perfiles.a", nil); err == nil {
+			t.Errorf("%q: se esperaba un error, se obtuvo %q", conf, raw.keys)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"mediacraft/i18n"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// Configuración integrada, base de todas las capas
+//
+//go:embed defaults.conf
+var defaultConf []byte
+
+// Nombre (sin extensión) del archivo de configuración en cada carpeta de
+// configuración: mediacraft.conf, .yaml, .yml, .toml o .json (ver findConf)
+const confName = "mediacraft"
+
+// Nombre (sin extensión) del archivo de configuración por carpeta (proyecto)
+const projectConfName = ".mediacraft"
+
+// Prefijo de las variables de entorno que sobrescriben la configuración
+const envPrefix = "MEDIACRAFT_"
+
+// Secciones a las que se puede llegar con MEDIACRAFT_<SECCION>_<CLAVE>; el resto de
+// variables MEDIACRAFT_<CLAVE> van a [mediacraft]
+var envSections = []string{"telegram", "watch", "servidor", "herramientas"}
+
+// Dir devuelve la carpeta de configuración del usuario: $XDG_CONFIG_HOME/mediacraft,
+// ~/.config/mediacraft o %USERPROFILE%\.config\mediacraft en Windows
+func Dir() (string, error) {
+	if runtime.GOOS != "windows" {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			return filepath.Join(xdg, "mediacraft"), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "mediacraft"), nil
+}
+
+// systemDirs devuelve las carpetas de configuración del sistema, de menor a mayor prioridad
+func systemDirs() []string {
+	if runtime.GOOS == "windows" {
+		if pd := os.Getenv("ProgramData"); pd != "" {
+			return []string{filepath.Join(pd, "mediacraft")}
+		}
+		return nil
+	}
+	dirs := []string{filepath.Join("/etc", "mediacraft")}
+	xdg := os.Getenv("XDG_CONFIG_DIRS")
+	if xdg == "" {
+		xdg = "/etc/xdg"
+	}
+	// XDG_CONFIG_DIRS va de mayor a menor prioridad
+	list := filepath.SplitList(xdg)
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i] != "" {
+			dirs = append(dirs, filepath.Join(list[i], "mediacraft"))
+		}
+	}
+	return dirs
+}
+
+// loadLayers combina, de menor a mayor prioridad: la configuración integrada, la del
+// sistema, la del usuario (o --config / MEDIACRAFT_CONFIG), la .mediacraft.conf de la
+// carpeta de trabajo, las variables MEDIACRAFT_* y los --set de la línea de órdenes.
+// En cada carpeta se usa el primer mediacraft.conf/.yaml/.yml/.toml/.json que exista.
+// Los archivos que no existen se omiten, salvo el indicado expresamente.
+// Devuelve también la lista de capas cargadas y los avisos sobre ellas.
+func loadLayers(opts LoadOptions) (*ini.File, []string, []string, error) {
+	cfg, err := ini.Load(defaultConf)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	builtin := builtinSections(cfg)
+	var warnings []string
+	var files []string
+	names := []string{i18n.T("(integrada)")}
+	add := func(p string) {
+		if p != "" {
+			files = append(files, p)
+		}
+	}
+	for _, d := range systemDirs() {
+		add(findConf(d, confName))
+	}
+	explicit := opts.File
+	if explicit == "" {
+		explicit = os.Getenv(envPrefix + "CONFIG")
+	}
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return nil, nil, nil, fmt.Errorf(i18n.T("no se encontró el archivo de configuración: %s"), explicit)
+		}
+		add(explicit)
+	} else if dir, err := Dir(); err == nil {
+		add(findConf(dir, confName))
+	}
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir, _ = os.Getwd()
+	}
+	if workDir != "" {
+		add(findConf(workDir, projectConfName))
+	}
+	for _, f := range files {
+		layer, err := readFile(f)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if w := checkSecretFile(f, layer); w != "" {
+			warnings = append(warnings, w)
+		}
+		replaceBuiltin(cfg, layer, builtin)
+		mergeFile(cfg, layer)
+		names = append(names, f)
+	}
+	if applyEnv(cfg) {
+		names = append(names, i18n.T("(entorno)"))
+	}
+	if len(opts.Overrides) > 0 {
+		if err := applyOverrides(cfg, opts.Overrides); err != nil {
+			return nil, nil, nil, err
+		}
+		names = append(names, "(--set)")
+	}
+	return cfg, names, warnings, nil
+}
+
+// builtinSections devuelve los perfiles y fragmentos de la configuración integrada
+func builtinSections(cfg *ini.File) map[string]bool {
+	res := map[string]bool{}
+	for _, sec := range cfg.Sections() {
+		if strings.HasPrefix(sec.Name(), "perfiles.") || strings.HasPrefix(sec.Name(), "fragment.") {
+			res[sec.Name()] = true
+		}
+	}
+	return res
+}
+
+// replaceBuiltin borra de cfg los perfiles y fragmentos integrados que define layer:
+// el de un archivo sustituye entero al integrado, sin heredar sus claves. Entre
+// archivos (sistema, usuario, proyecto) se siguen combinando clave a clave.
+func replaceBuiltin(cfg, layer *ini.File, builtin map[string]bool) {
+	for _, sec := range layer.Sections() {
+		if builtin[sec.Name()] {
+			cfg.DeleteSection(sec.Name())
+			delete(builtin, sec.Name())
+		}
+	}
+}
+
+// applyEnv aplica las variables MEDIACRAFT_<CLAVE> (sección [mediacraft]) y
+// MEDIACRAFT_<SECCION>_<CLAVE>. Devuelve si había alguna.
+func applyEnv(cfg *ini.File) bool {
+	var names []string
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, envPrefix) {
+			names = append(names, e)
+		}
+	}
+	sort.Strings(names)
+	applied := false
+	for _, e := range names {
+		eq := strings.Index(e, "=")
+		if eq < 0 {
+			continue
+		}
+		name := strings.ToLower(e[len(envPrefix):eq])
+		if name == "config" || name == "" {
+			continue
+		}
+		section, key := "mediacraft", name
+		for _, s := range envSections {
+			if strings.HasPrefix(name, s+"_") {
+				section, key = s, name[len(s)+1:]
+				break
+			}
+		}
+		cfg.Section(section).Key(key).SetValue(e[eq+1:])
+		applied = true
+	}
+	return applied
+}
+
+// applyOverrides aplica valores seccion.clave=valor; sin sección se usa [mediacraft].
+// La sección es lo que hay antes del último punto (perfiles.movil.kvideo=900k).
+func applyOverrides(cfg *ini.File, overrides []string) error {
+	for _, o := range overrides {
+		eq := strings.Index(o, "=")
+		if eq <= 0 {
+			return fmt.Errorf(i18n.T("--set %s: se esperaba seccion.clave=valor"), o)
+		}
+		name, value := strings.TrimSpace(o[:eq]), strings.TrimSpace(o[eq+1:])
+		section, key := "mediacraft", name
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			section, key = name[:dot], name[dot+1:]
+		}
+		if section == "" || key == "" {
+			return fmt.Errorf(i18n.T("--set %s: se esperaba seccion.clave=valor"), o)
+		}
+		cfg.Section(section).Key(key).SetValue(value)
+	}
+	return nil
+}
+
+// DirectoryProfile busca un .mediacraft.conf (o .yaml, .toml, .json) en dir o en sus carpetas superiores y
+// devuelve el perfil que fija para esa carpeta (default_profile en [mediacraft] o
+// perfil al principio del archivo), y el archivo en el que está. Vacío si no hay.
+func DirectoryProfile(dir string) (profile, file string) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ""
+	}
+	for {
+		if p := findConf(abs, projectConfName); p != "" {
+			cfg, err := readFile(p)
+			if err != nil {
+				return "", ""
+			}
+			for _, k := range []string{"mediacraft.default_profile", ".perfil", "mediacraft.perfil"} {
+				dot := strings.Index(k, ".")
+				sec := cfg.Section(k[:dot])
+				if k[:dot] == "" {
+					sec = cfg.Section(ini.DefaultSection)
+				}
+				if v := strings.TrimSpace(sec.Key(k[dot+1:]).String()); v != "" {
+					return v, p
+				}
+			}
+			return "", p
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", ""
+		}
+		abs = parent
+	}
+}
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"mediacraft/i18n"
+	"mediacraft/targets"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// Profile es un perfil de conversión ya compilado: las opciones de ffmpeg separadas
+// por el lado en que van (entrada o salida) y por tipo de pista
+type Profile struct {
+	Name     string
+	Ext      string
+	HWAccel  string            // Acelerador indicado en el perfil ("none" si se desactiva expresamente)
+	Input    []string          // Opciones de entrada, antes de -i (hwaccel...)
+	Video    []string          // Opciones de la pista de vídeo (-vn si video = none)
+	Audio    []string          // Opciones de la pista de audio (-an si audio = none)
+	Subs     []string          // Opciones de subtítulos (-sn si subtitulos = none)
+	Output   []string          // Resto de opciones de salida
+	Options  map[string]string // Opciones propias de MediaCraft (no de ffmpeg)
+	Extends  []string          // Cadena de herencia (extends), del padre al más lejano
+	Includes []string          // Fragmentos incluidos, directa o indirectamente
+}
+
+// OutputArgs devuelve todas las opciones de salida del perfil, en orden vídeo,
+// audio, subtítulos y resto
+func (p Profile) OutputArgs() []string {
+	args := append([]string{}, p.Video...)
+	args = append(args, p.Audio...)
+	args = append(args, p.Subs...)
+	return append(args, p.Output...)
+}
+
+// Tipo de pista al que afecta una clave del perfil
+const (
+	streamVideo = "video"
+	streamAudio = "audio"
+	streamOther = ""
+)
+
+// profileKey describe una clave de perfil que se traduce a una opción de ffmpeg
+type profileKey struct {
+	flag   string
+	stream string
+	check  func(v string) error
+}
+
+// Claves de perfil que se traducen directamente a una opción de salida de ffmpeg.
+// Los valores se pasan tal cual (vf = scale=640:-2 conserva el =).
+var profileKeys = map[string]profileKey{
+	"kvideo":   {"-b:v", streamVideo, checkBitrate},
+	"kaudio":   {"-b:a", streamAudio, checkBitrate},
+	"preset":   {"-preset", streamVideo, nil},
+	"tune":     {"-tune", streamVideo, nil},
+	"pix_fmt":  {"-pix_fmt", streamVideo, nil},
+	"vf":       {"-vf", streamVideo, nil},
+	"fps":      {"-r", streamVideo, checkPositive},
+	"gop":      {"-g", streamVideo, checkInt(1, 10000)},
+	"maxrate":  {"-maxrate", streamVideo, checkBitrate},
+	"bufsize":  {"-bufsize", streamVideo, checkBitrate},
+	"af":       {"-af", streamAudio, nil},
+	"canales":  {"-ac", streamAudio, checkInt(1, 16)},
+	"muestreo": {"-ar", streamAudio, checkInt(8000, 192000)},
+	"movflags": {"-movflags", streamOther, nil},
+}
+
+// Opciones propias de MediaCraft que se guardan en Profile.Options
+var mediacraftKeys = map[string]bool{
+	"metadatos": true, "portada": true, "capitulos": true, "adjuntos": true,
+	"metadatos_origen": true, "output_name": true, "remux": true, "target": true, "pasadas": true,
+}
+
+// Aceleradores por hardware que admite -hwaccel
+var hwaccels = map[string]bool{
+	"auto": true, "cuda": true, "qsv": true, "vaapi": true, "d3d11va": true, "dxva2": true,
+	"videotoolbox": true, "vulkan": true, "drm": true, "opencl": true,
+}
+
+var (
+	bitrateRe = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kKmM]?$`)
+	extRe     = regexp.MustCompile(`^\.?[A-Za-z0-9]+$`)
+)
+
+// compileProfile traduce una sección [perfiles.x] a un Profile, resolviendo antes
+// extends e include, y validando cada clave. Los errores indican la sección y la
+// clave afectadas (y de qué perfil o fragmento viene si es heredada).
+func compileProfile(cfg *ini.File, name string) (Profile, error) {
+	p := Profile{Name: strings.TrimPrefix(name, "perfiles."), Options: map[string]string{}}
+	raw, err := resolveSection(cfg, name, nil)
+	if err != nil {
+		return p, err
+	}
+	p.Extends, p.Includes = raw.extends, raw.includes
+	var errs []error
+	fail := func(key, format string, a ...interface{}) {
+		where := key
+		if kv, ok := raw.values[key]; ok && kv.origin != name {
+			where = fmt.Sprintf(i18n.T("%s (de %s)"), key, kv.origin)
+		}
+		errs = append(errs, fmt.Errorf("[%s] %s: %s", name, where, fmt.Sprintf(i18n.T(format), a...)))
+	}
+	keys := raw.keys
+	values := map[string]string{}
+	for k, kv := range raw.values {
+		values[k] = kv.value
+	}
+	videoOff := strings.EqualFold(values["video"], "none")
+	audioOff := strings.EqualFold(values["audio"], "none")
+	subsOff := strings.EqualFold(values["subtitulos"], "none")
+
+	// Códecs primero: el resto de opciones de cada pista va detrás
+	switch {
+	case values["video"] == "":
+	case videoOff:
+		p.Video = append(p.Video, "-vn")
+	default:
+		p.Video = append(p.Video, "-c:v", values["video"])
+	}
+	switch {
+	case values["audio"] == "":
+	case audioOff:
+		p.Audio = append(p.Audio, "-an")
+	default:
+		p.Audio = append(p.Audio, "-c:a", values["audio"])
+	}
+	switch {
+	case values["subtitulos"] == "":
+	case subsOff:
+		p.Subs = append(p.Subs, "-sn")
+	default:
+		p.Subs = append(p.Subs, "-c:s", values["subtitulos"])
+	}
+
+	for _, k := range keys {
+		v := values[k]
+		switch {
+		case k == "video" || k == "audio" || k == "subtitulos":
+		case k == "ext":
+			if !extRe.MatchString(v) {
+				fail(k, "extensión no válida %q", v)
+				continue
+			}
+			p.Ext = v
+		case k == "hwaccel":
+			v = strings.ToLower(v)
+			p.HWAccel = v
+			if v == "none" {
+				continue
+			}
+			if !hwaccels[v] {
+				fail(k, "acelerador desconocido %q", v)
+				continue
+			}
+			if videoOff {
+				fail(k, "no tiene sentido con video = none")
+				continue
+			}
+			p.Input = append(p.Input, "-hwaccel", v)
+		case k == "crf":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > 63 {
+				fail(k, "se esperaba un número entre 0 y 63, no %q", v)
+				continue
+			}
+			if videoOff {
+				fail(k, "no tiene sentido con video = none")
+				continue
+			}
+			p.Video = append(p.Video, qualityFlag(values["video"]), v)
+		case k == "opciones_entrada" || k == "opciones_salida":
+			args, err := splitArgs(v)
+			if err != nil {
+				fail(k, "%v", err)
+				continue
+			}
+			if k == "opciones_entrada" {
+				p.Input = append(p.Input, args...)
+			} else {
+				p.Output = append(p.Output, args...)
+			}
+		case k == "target":
+			if _, ok := targets.Get(v); !ok {
+				fail(k, "destino desconocido %q (disponibles: %s)", v, strings.Join(targets.Names(), ", "))
+				continue
+			}
+			p.Options[k] = strings.ToLower(v)
+		case k == "pasadas":
+			if err := checkInt(1, 2)(v); err != nil {
+				fail(k, "%v", err)
+				continue
+			}
+			if videoOff {
+				fail(k, "no tiene sentido con video = none")
+				continue
+			}
+			p.Options[k] = v
+		case mediacraftKeys[k]:
+			p.Options[k] = v
+		default:
+			pk, ok := profileKeys[k]
+			if !ok {
+				fail(k, "clave desconocida (use opciones_salida para pasar opciones de ffmpeg)")
+				continue
+			}
+			if pk.check != nil {
+				if err := pk.check(v); err != nil {
+					fail(k, "%v", err)
+					continue
+				}
+			}
+			switch pk.stream {
+			case streamVideo:
+				if videoOff {
+					fail(k, "no tiene sentido con video = none")
+					continue
+				}
+				p.Video = append(p.Video, pk.flag, v)
+			case streamAudio:
+				if audioOff {
+					fail(k, "no tiene sentido con audio = none")
+					continue
+				}
+				p.Audio = append(p.Audio, pk.flag, v)
+			default:
+				p.Output = append(p.Output, pk.flag, v)
+			}
+		}
+	}
+	if videoOff && audioOff {
+		fail("video", "el perfil descarta vídeo y audio")
+	}
+	return p, errors.Join(errs...)
+}
+
+// qualityFlag devuelve la opción de calidad constante de cada familia de codificadores
+func qualityFlag(encoder string) string {
+	switch {
+	case strings.HasSuffix(encoder, "_nvenc"):
+		return "-cq"
+	case strings.HasSuffix(encoder, "_qsv"):
+		return "-global_quality"
+	default:
+		return "-crf"
+	}
+}
+
+func checkBitrate(v string) error {
+	if !bitrateRe.MatchString(v) {
+		return fmt.Errorf(i18n.T("tasa de bits no válida %q (p.ej. 2500k, 5M)"), v)
+	}
+	return nil
+}
+
+func checkPositive(v string) error {
+	if n, err := strconv.ParseFloat(v, 64); err != nil || n <= 0 {
+		return fmt.Errorf(i18n.T("se esperaba un número positivo, no %q"), v)
+	}
+	return nil
+}
+
+func checkInt(lo, hi int) func(string) error {
+	return func(v string) error {
+		if n, err := strconv.Atoi(v); err != nil || n < lo || n > hi {
+			return fmt.Errorf(i18n.T("se esperaba un número entre %d y %d, no %q"), lo, hi, v)
+		}
+		return nil
+	}
+}
+
+// splitArgs separa una línea de opciones en argumentos, respetando comillas dobles
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inQuotes, started := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			started = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if started {
+				args = append(args, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuotes {
+		return nil, errors.New(i18n.T("comillas sin cerrar"))
+	}
+	if started {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
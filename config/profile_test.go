@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+// compileTest compila el perfil x de una configuración escrita en INI
+func compileTest(t *testing.T, conf string) (Profile, error) {
+	t.Helper()
+	f, err := ini.Load([]byte(conf))
+	if err != nil {
+		t.Fatalf("INI no válido: %v", err)
+	}
+	return compileProfile(f, "perfiles.x")
+}
+
+func TestCompileProfile(t *testing.T) {
+	p, err := compileTest(t, `[perfiles.x]
+ext = mp4
+hwaccel = cuda
+video = hevc_nvenc
+crf = 24
+kvideo = 5M
+audio = aac
+kaudio = 128k
+canales = 2
+subtitulos = none
+movflags = +faststart
+opciones_salida = -metadata "comment=a b"
+remux = no
+pasadas = 2
+`)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	want := Profile{
+		Name:    "x",
+		Ext:     "mp4",
+		HWAccel: "cuda",
+		Input:   []string{"-hwaccel", "cuda"},
+		Video:   []string{"-c:v", "hevc_nvenc", "-cq", "24", "-b:v", "5M"},
+		Audio:   []string{"-c:a", "aac", "-b:a", "128k", "-ac", "2"},
+		Subs:    []string{"-sn"},
+		Output:  []string{"-movflags", "+faststart", "-metadata", "comment=a b"},
+		Options: map[string]string{"remux": "no", "pasadas": "2"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("compileProfile =\n %+v\nse esperaba\n %+v", p, want)
+	}
+
+	// Calidad constante según la familia del codificador
+	for encoder, want := range map[string][]string{
+		"h264_nvenc": {"-c:v", "h264_nvenc", "-cq", "20"},
+		"hevc_qsv":   {"-c:v", "hevc_qsv", "-global_quality", "20"},
+		"libx264":    {"-c:v", "libx264", "-crf", "20"},
+	} {
+		p, err := compileTest(t, "[perfiles.x]\nvideo = "+encoder+"\ncrf = 20\n")
+		if err != nil {
+			t.Errorf("video = %s: error inesperado: %v", encoder, err)
+		} else if !reflect.DeepEqual(p.Video, want) {
+			t.Errorf("video = %s: %q, se esperaba %q", encoder, p.Video, want)
+		}
+	}
+}
+
+func TestCompileProfileErrors(t *testing.T) {
+	for _, conf := range []string{
+		"ext = .m p4",
+		"hwaccel = gpu",
+		"crf = 64",
+		"crf = alto",
+		"kvideo = 5 megas",
+		"canales = 0",
+		"muestreo = 100",
+		"fps = -1",
+		"gop = 0",
+		"bitrate = 5M",
+		"target = nevera",
+		"pasadas = 3",
+		`opciones_salida = -metadata "title=a`,
+		"video = none\nkvideo = 2M",
+		"video = none\nhwaccel = cuda",
+		"video = none\npasadas = 2",
+		"audio = none\nkaudio = 128k",
+		"video = none\naudio = none",
+	} {
+		if p, err := compileTest(t, "[perfiles.x]\n"+conf+"\n"); err == nil {
+			t.Errorf("%q: se esperaba un error, se obtuvo %+v", conf, p)
+		}
+	}
+}
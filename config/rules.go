@@ -0,0 +1,307 @@
+package config
+
+import (
+	"fmt"
+	"mediacraft/i18n"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// Clave de [reglas] con el perfil a usar si no coincide ninguna regla
+const rulesDefaultKey = "defecto"
+
+// Rule es una regla de [reglas]: nombre = condición [y condición...] -> perfil.
+// Si se cumplen todas sus condiciones, el archivo se convierte con Profile.
+type Rule struct {
+	Name       string
+	Conditions []Condition
+	Profile    string // Perfil o lista de perfiles (telegram,plex)
+}
+
+// Condition es una comparación de un dato del archivo con un valor
+type Condition struct {
+	Field string  // alto, ancho, duracion, tamaño, ruta, nombre o ext
+	Op    string  // >, >=, <, <=, =, != o contiene
+	Text  string  // Valor tal como se escribió
+	num   float64 // Valor numérico (segundos, bytes o píxeles)
+}
+
+// MediaFacts son los datos de un archivo con los que se evalúan las reglas. Los datos
+// numéricos a 0 son desconocidos (p.ej. sin ffprobe) y no cumplen ninguna condición.
+type MediaFacts struct {
+	Path     string
+	Size     int64   // Bytes
+	Duration float64 // Segundos
+	Width    int
+	Height   int
+}
+
+// Campos de las condiciones y sus sinónimos
+var ruleFields = map[string]string{
+	"alto": "alto", "altura": "alto", "resolucion": "alto", "resolución": "alto",
+	"ancho": "ancho", "anchura": "ancho",
+	"duracion": "duracion", "duración": "duracion",
+	"tamaño": "tamaño", "tamano": "tamaño",
+	"ruta": "ruta", "nombre": "nombre", "ext": "ext", "extension": "ext", "extensión": "ext",
+}
+
+// Operadores, los de dos caracteres primero para que >= no se lea como >
+var ruleOps = []string{">=", "<=", "!=", ">", "<", "=", "contiene"}
+
+// String devuelve la regla en el formato de la configuración
+func (r Rule) String() string {
+	return r.When() + " -> " + r.Profile
+}
+
+// When devuelve las condiciones de la regla (alto >= 2160 y duracion > 1h)
+func (r Rule) When() string {
+	conds := make([]string, len(r.Conditions))
+	for i, c := range r.Conditions {
+		conds[i] = c.String()
+	}
+	return strings.Join(conds, " y ")
+}
+
+func (c Condition) String() string {
+	return c.Field + " " + c.Op + " " + c.Text
+}
+
+// Matches indica si el archivo cumple todas las condiciones de la regla
+func (r Rule) Matches(f MediaFacts) bool {
+	for _, c := range r.Conditions {
+		if !c.Matches(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches indica si el archivo cumple la condición. Las rutas se comparan con / y sin
+// distinguir mayúsculas, para que "ruta contiene /Musica/" valga también en Windows.
+func (c Condition) Matches(f MediaFacts) bool {
+	var v float64
+	switch c.Field {
+	case "ruta", "nombre", "ext":
+		s := filepath.ToSlash(f.Path)
+		switch c.Field {
+		case "nombre":
+			s = filepath.Base(f.Path)
+		case "ext":
+			s = strings.TrimPrefix(filepath.Ext(f.Path), ".")
+		}
+		s, want := strings.ToLower(s), strings.ToLower(strings.TrimPrefix(c.Text, "."))
+		switch c.Op {
+		case "contiene":
+			return strings.Contains(s, want)
+		case "=":
+			return s == want
+		case "!=":
+			return s != want
+		}
+		return false
+	case "alto":
+		v = float64(f.Height)
+	case "ancho":
+		v = float64(f.Width)
+	case "duracion":
+		v = f.Duration
+	case "tamaño":
+		v = float64(f.Size)
+	}
+	if v <= 0 {
+		return false
+	}
+	switch c.Op {
+	case ">":
+		return v > c.num
+	case ">=":
+		return v >= c.num
+	case "<":
+		return v < c.num
+	case "<=":
+		return v <= c.num
+	case "=":
+		return v == c.num
+	case "!=":
+		return v != c.num
+	}
+	return false
+}
+
+// MatchRule devuelve la primera regla que cumple el archivo
+func (c *Config) MatchRule(f MediaFacts) (Rule, bool) {
+	for _, r := range c.Rules {
+		if r.Matches(f) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// parseRules lee la sección [reglas], en el orden en que están escritas
+func (c *Config) parseRules(cfg *ini.File) error {
+	sec, err := cfg.GetSection("reglas")
+	if err != nil {
+		return nil
+	}
+	for _, key := range sec.Keys() {
+		name, value := key.Name(), strings.TrimSpace(key.String())
+		if name == rulesDefaultKey {
+			if err := c.checkRuleProfile(name, value); err != nil {
+				return err
+			}
+			c.RulesDefault = value
+			continue
+		}
+		r, err := parseRule(name, value)
+		if err != nil {
+			return err
+		}
+		if err := c.checkRuleProfile(name, r.Profile); err != nil {
+			return err
+		}
+		c.Rules = append(c.Rules, r)
+	}
+	return nil
+}
+
+// checkRuleProfile comprueba que existen los perfiles de una regla
+func (c *Config) checkRuleProfile(name, list string) error {
+	if strings.TrimSpace(list) == "" {
+		return fmt.Errorf(i18n.T("[reglas] %s: falta el perfil"), name)
+	}
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); !c.HasProfile(p) {
+			return fmt.Errorf(i18n.T("[reglas] %s: perfil desconocido %q"), name, p)
+		}
+	}
+	return nil
+}
+
+// parseRule lee "condición [y condición...] -> perfil"
+func parseRule(name, value string) (Rule, error) {
+	r := Rule{Name: name}
+	cond, profile, ok := strings.Cut(value, "->")
+	if !ok {
+		cond, profile, ok = strings.Cut(value, "→")
+	}
+	if !ok {
+		return r, fmt.Errorf(i18n.T("[reglas] %s: se esperaba condición -> perfil"), name)
+	}
+	r.Profile = strings.TrimSpace(profile)
+	for _, part := range splitConditions(cond) {
+		c, err := parseCondition(strings.TrimSpace(part))
+		if err != nil {
+			return r, fmt.Errorf("[reglas] %s: %v", name, err)
+		}
+		r.Conditions = append(r.Conditions, c)
+	}
+	return r, nil
+}
+
+// splitConditions separa las condiciones unidas por " y ". Sólo se corta fuera de
+// comillas dobles y cuando lo que sigue empieza por un campo conocido, así que los valores
+// de texto pueden contener " y " (ruta contiene /Series y Pelis/).
+func splitConditions(cond string) []string {
+	var parts []string
+	start, inQuotes := 0, false
+	for i, r := range cond {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && strings.HasPrefix(cond[i:], " y ") && startsWithField(cond[i+3:]):
+			parts = append(parts, cond[start:i])
+			start = i + 3
+		}
+	}
+	return append(parts, cond[start:])
+}
+
+// startsWithField indica si s empieza por un campo de regla (alto, ruta...)
+func startsWithField(s string) bool {
+	field := strings.TrimLeft(s, " ")
+	if i := strings.IndexAny(field, " <>=!"); i >= 0 {
+		field = field[:i]
+	}
+	_, ok := ruleFields[strings.ToLower(field)]
+	return ok
+}
+
+// parseCondition lee "campo operador valor" (p.ej. alto >= 2160, duracion < 10m,
+// tamaño > 4GB, ruta contiene /Musica/)
+func parseCondition(s string) (Condition, error) {
+	var c Condition
+	field, rest, _ := strings.Cut(s, " ")
+	if fields := strings.FieldsFunc(field, func(r rune) bool { return strings.ContainsRune("<>=!", r) }); len(fields) > 0 && fields[0] != field {
+		// Sin espacio entre campo y operador: alto>=2160
+		rest = strings.TrimPrefix(field, fields[0]) + " " + rest
+		field = fields[0]
+	}
+	var ok bool
+	if c.Field, ok = ruleFields[strings.ToLower(field)]; !ok {
+		return c, fmt.Errorf(i18n.T("campo desconocido %q (alto, ancho, duracion, tamaño, ruta, nombre o ext)"), field)
+	}
+	rest = strings.TrimSpace(rest)
+	for _, op := range ruleOps {
+		if strings.HasPrefix(rest, op) {
+			c.Op = op
+			c.Text = strings.Trim(strings.TrimSpace(rest[len(op):]), `"'`)
+			break
+		}
+	}
+	if c.Op == "" {
+		return c, fmt.Errorf(i18n.T("%s: falta el operador (>, >=, <, <=, =, != o contiene)"), s)
+	}
+	if c.Text == "" {
+		return c, fmt.Errorf(i18n.T("%s: falta el valor"), s)
+	}
+	var err error
+	switch c.Field {
+	case "ruta", "nombre", "ext":
+		if c.Op != "=" && c.Op != "!=" && c.Op != "contiene" {
+			return c, fmt.Errorf(i18n.T("%s: %s sólo admite =, != o contiene"), s, c.Field)
+		}
+		return c, nil
+	case "duracion":
+		c.num, err = parseSeconds(c.Text)
+	case "tamaño":
+		c.num, err = parseBytes(c.Text)
+	default:
+		c.num, err = strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(c.Text), "p"), 64)
+	}
+	if err != nil || c.Op == "contiene" {
+		return c, fmt.Errorf(i18n.T("%s: valor no válido para %s"), s, c.Field)
+	}
+	return c, nil
+}
+
+// parseSeconds lee una duración: segundos o con unidades (90s, 10m, 1h30m)
+func parseSeconds(s string) (float64, error) {
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n, nil
+	}
+	d, err := time.ParseDuration(s)
+	return d.Seconds(), err
+}
+
+// parseBytes lee un tamaño: bytes o con unidades en base 1024 (700MB, 4GB, 4G)
+func parseBytes(s string) (float64, error) {
+	units := []struct {
+		suffix string
+		mult   float64
+	}{{"tb", 1 << 40}, {"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10}, {"t", 1 << 40}, {"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}, {"b", 1}}
+	lower := strings.ToLower(strings.TrimSpace(s))
+	mult := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(lower, u.suffix) {
+			lower, mult = strings.TrimSpace(strings.TrimSuffix(lower, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.Replace(lower, ",", ".", 1), 64)
+	return n * mult, err
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Rule
+	}{
+		{"alto >= 2160 -> archivo", Rule{Name: "r", Profile: "archivo", Conditions: []Condition{{"alto", ">=", "2160", 2160}}}},
+		{"alto>=2160->archivo", Rule{Name: "r", Profile: "archivo", Conditions: []Condition{{"alto", ">=", "2160", 2160}}}},
+		{"duracion < 10m → instagram", Rule{Name: "r", Profile: "instagram", Conditions: []Condition{{"duracion", "<", "10m", 600}}}},
+		{"resolución >= 1080p y tamaño > 4GB -> telegram,plex", Rule{Name: "r", Profile: "telegram,plex", Conditions: []Condition{
+			{"alto", ">=", "1080p", 1080}, {"tamaño", ">", "4GB", 4 << 30},
+		}}},
+		{`ruta contiene "/Musica/" -> audio`, Rule{Name: "r", Profile: "audio", Conditions: []Condition{{"ruta", "contiene", "/Musica/", 0}}}},
+		// " y " dentro de un valor no separa condiciones
+		{"ruta contiene /Series y Pelis/ -> plex", Rule{Name: "r", Profile: "plex", Conditions: []Condition{{"ruta", "contiene", "/Series y Pelis/", 0}}}},
+		{`alto >= 1080 y nombre contiene "a y alto" -> plex`, Rule{Name: "r", Profile: "plex", Conditions: []Condition{
+			{"alto", ">=", "1080", 1080}, {"nombre", "contiene", "a y alto", 0},
+		}}},
+		{"nombre contiene l'amour y alto > 1080 -> plex", Rule{Name: "r", Profile: "plex", Conditions: []Condition{
+			{"nombre", "contiene", "l'amour", 0}, {"alto", ">", "1080", 1080},
+		}}},
+		{"ruta contiene /Series y Pelis/ y ext = mkv -> plex", Rule{Name: "r", Profile: "plex", Conditions: []Condition{
+			{"ruta", "contiene", "/Series y Pelis/", 0}, {"ext", "=", "mkv", 0},
+		}}},
+		{"extensión != avi -> telegram", Rule{Name: "r", Profile: "telegram", Conditions: []Condition{{"ext", "!=", "avi", 0}}}},
+		// Sin perfil: lo comprueba checkRuleProfile
+		{"ancho > 1920 ->", Rule{Name: "r", Conditions: []Condition{{"ancho", ">", "1920", 1920}}}},
+	}
+	for _, tt := range tests {
+		got, err := parseRule("r", tt.value)
+		if err != nil {
+			t.Errorf("parseRule(%q): error inesperado: %v", tt.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRule(%q) = %+v, se esperaba %+v", tt.value, got, tt.want)
+		}
+	}
+
+	// Reglas mal escritas
+	for _, value := range []string{
+		"alto >= 2160",
+		"-> archivo",
+		"alto >= 2160 y -> archivo",
+		"alto >= 2160 y bitrate > 5M -> archivo",
+	} {
+		if r, err := parseRule("r", value); err == nil {
+			t.Errorf("parseRule(%q) = %q, se esperaba un error", value, r)
+		}
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	valid := map[string]Condition{
+		"alto >= 2160":           {"alto", ">=", "2160", 2160},
+		"altura>1080p":           {"alto", ">", "1080p", 1080},
+		"ANCHO <= 1280":          {"ancho", "<=", "1280", 1280},
+		"duracion < 90":          {"duracion", "<", "90", 90},
+		"duración > 1h30m":       {"duracion", ">", "1h30m", 5400},
+		"tamaño > 4GB":           {"tamaño", ">", "4GB", 4 << 30},
+		"tamano < 700 m":         {"tamaño", "<", "700 m", 700 << 20},
+		"tamaño = 1,5k":          {"tamaño", "=", "1,5k", 1536},
+		"nombre contiene sample": {"nombre", "contiene", "sample", 0},
+		"ext = 'mkv'":            {"ext", "=", "mkv", 0},
+		"ruta != /tmp/":          {"ruta", "!=", "/tmp/", 0},
+	}
+	for s, want := range valid {
+		got, err := parseCondition(s)
+		if err != nil {
+			t.Errorf("parseCondition(%q): error inesperado: %v", s, err)
+		} else if got != want {
+			t.Errorf("parseCondition(%q) = %+v, se esperaba %+v", s, got, want)
+		}
+	}
+
+	invalid := []string{
+		"",                      // Vacía
+		"bitrate > 5M",          // Campo desconocido
+		"alto",                  // Sin operador ni valor
+		"alto 2160",             // Sin operador
+		"alto >=",               // Sin valor
+		`ext = ""`,              // Valor vacío entre comillas
+		"ruta > /tmp/",          // Operador numérico en un campo de texto
+		"nombre >= a",           // Operador numérico en un campo de texto
+		"alto >= mucho",         // Valor no numérico
+		"duracion > 10 minutos", // Unidad de tiempo no válida
+		"tamaño > 4XB",          // Unidad de tamaño no válida
+		"alto contiene 1080",    // contiene en un campo numérico
+	}
+	for _, s := range invalid {
+		if c, err := parseCondition(s); err == nil {
+			t.Errorf("parseCondition(%q) = %q, se esperaba un error", s, c)
+		}
+	}
+}
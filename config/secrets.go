@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"mediacraft/i18n"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// Claves que contienen secretos, por sección. Admiten env:VARIABLE y file:/ruta y su
+// valor no se muestra nunca (ver Redact).
+var secretKeys = map[string][]string{
+	"telegram": {"token"},
+	"servidor": {"token"},
+}
+
+// Texto que sustituye a los secretos en la salida
+const redacted = "****"
+
+// isSecretRef indica si un valor es una referencia a un secreto y no el secreto en sí
+func isSecretRef(v string) bool {
+	return strings.HasPrefix(v, "env:") || strings.HasPrefix(v, "file:")
+}
+
+// worldReadable indica si cualquier usuario del sistema puede leer el archivo
+func worldReadable(path string) bool {
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().Perm()&0o004 != 0
+}
+
+// checkSecretFile devuelve un aviso si el archivo de configuración lo puede leer
+// cualquier usuario, más grave si tiene algún secreto escrito tal cual
+func checkSecretFile(path string, cfg *ini.File) string {
+	if !worldReadable(path) {
+		return ""
+	}
+	var found []string
+	for _, section := range sortedSecretSections() {
+		sec, err := cfg.GetSection(section)
+		if err != nil {
+			continue
+		}
+		for _, key := range secretKeys[section] {
+			if v := strings.TrimSpace(sec.Key(key).String()); v != "" && !isSecretRef(v) {
+				found = append(found, fmt.Sprintf("[%s] %s", section, key))
+			}
+		}
+	}
+	if len(found) == 0 {
+		return fmt.Sprintf(i18n.T("%s lo puede leer cualquier usuario; use chmod 600 antes de guardar secretos en él"), path)
+	}
+	return fmt.Sprintf(i18n.T("%s contiene secretos (%s) y lo puede leer cualquier usuario; use chmod 600 o referencias env:/file:"), path, strings.Join(found, ", "))
+}
+
+func sortedSecretSections() []string {
+	names := make([]string, 0, len(secretKeys))
+	for s := range secretKeys {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// secret devuelve el valor de una clave secreta: el propio valor, el de la variable de
+// entorno de env:VARIABLE o el contenido de file:/ruta (sin el salto de línea final).
+// El resultado se recuerda para Redact.
+func (c *Config) secret(section, key, v string) (string, error) {
+	v = strings.TrimSpace(v)
+	switch {
+	case strings.HasPrefix(v, "env:"):
+		name := strings.TrimPrefix(v, "env:")
+		val := os.Getenv(name)
+		if val == "" {
+			return "", fmt.Errorf(i18n.T("[%s] %s: la variable de entorno %s no está definida"), section, key, name)
+		}
+		v = val
+	case strings.HasPrefix(v, "file:"):
+		path := strings.TrimPrefix(v, "file:")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf(i18n.T("[%s] %s: no se pudo leer el secreto: %v"), section, key, err)
+		}
+		if worldReadable(path) {
+			c.Warnings = append(c.Warnings, fmt.Sprintf(i18n.T("[%s] %s: %s lo puede leer cualquier usuario; use chmod 600"), section, key, path))
+		}
+		v = strings.TrimRight(string(data), "\r\n")
+	}
+	if v != "" {
+		c.secrets = append(c.secrets, v)
+		// Los más largos primero, por si uno contiene a otro
+		sort.Slice(c.secrets, func(i, j int) bool { return len(c.secrets[i]) > len(c.secrets[j]) })
+	}
+	return v, nil
+}
+
+// Redact sustituye por **** los secretos de la configuración que aparezcan en s.
+// Se usa en todo lo que se muestra o se guarda: mensajes, registros y ensayos.
+func (c *Config) Redact(s string) string {
+	for _, sec := range c.secrets {
+		s = strings.ReplaceAll(s, sec, redacted)
+	}
+	return s
+}
+
+// RedactWriter devuelve un io.Writer que escribe en w con los secretos ocultos,
+// o w tal cual si no hay secretos
+func (c *Config) RedactWriter(w io.Writer) io.Writer {
+	if w == nil || len(c.secrets) == 0 {
+		return w
+	}
+	return redactWriter{c, w}
+}
+
+type redactWriter struct {
+	c *Config
+	w io.Writer
+}
+
+func (r redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, r.c.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+func TestSecret(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tg")
+	if err := os.WriteFile(file, []byte("desde-archivo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MEDIACRAFT_TEST_TOKEN", "desde-entorno")
+	var c Config
+	tests := []struct {
+		value, want string
+		wantErr     bool
+	}{
+		{value: " tal-cual ", want: "tal-cual"},
+		{value: "env:MEDIACRAFT_TEST_TOKEN", want: "desde-entorno"},
+		{value: "file:" + file, want: "desde-archivo"},
+		{value: "", want: ""},
+		{value: "env:MEDIACRAFT_TEST_NO_EXISTE", wantErr: true},
+		{value: "file:" + filepath.Join(dir, "no-existe"), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := c.secret("telegram", "token", tt.value)
+		switch {
+		case tt.wantErr && err == nil:
+			t.Errorf("secret(%q) = %q, se esperaba un error", tt.value, got)
+		case !tt.wantErr && err != nil:
+			t.Errorf("secret(%q): error inesperado: %v", tt.value, err)
+		case got != tt.want:
+			t.Errorf("secret(%q) = %q, se esperaba %q", tt.value, got, tt.want)
+		}
+	}
+	if len(c.secrets) != 3 {
+		t.Errorf("se recuerdan %d secretos, se esperaban 3: %q", len(c.secrets), c.secrets)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	var c Config
+	if got := c.Redact("sin secretos"); got != "sin secretos" {
+		t.Errorf("Redact sin secretos = %q", got)
+	}
+	// Un secreto que contiene a otro se oculta entero
+	for _, v := range []string{"123", "123:ABC"} {
+		if _, err := c.secret("telegram", "token", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for in, want := range map[string]string{
+		"https://api.telegram.org/bot123:ABC/sendMessage": "https://api.telegram.org/bot****/sendMessage",
+		"chat 123 y 123:ABC":                              "chat **** y ****",
+		"nada que ocultar":                                "nada que ocultar",
+	} {
+		if got := c.Redact(in); got != want {
+			t.Errorf("Redact(%q) = %q, se esperaba %q", in, got, want)
+		}
+	}
+}
+
+func TestRedactWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var c Config
+	if w := c.RedactWriter(&buf); w != &buf {
+		t.Errorf("sin secretos RedactWriter debe devolver el mismo writer")
+	}
+	if w := c.RedactWriter(nil); w != nil {
+		t.Errorf("RedactWriter(nil) = %v, se esperaba nil", w)
+	}
+	if _, err := c.secret("servidor", "token", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	w := c.RedactWriter(&buf)
+	line := []byte("Authorization: Bearer s3cret\n")
+	n, err := w.Write(line)
+	if err != nil || n != len(line) {
+		t.Errorf("Write = %d, %v; se esperaba %d, nil", n, err, len(line))
+	}
+	if got, want := buf.String(), "Authorization: Bearer ****\n"; got != want {
+		t.Errorf("se escribió %q, se esperaba %q", got, want)
+	}
+}
+
+func TestCheckSecretFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sin permisos Unix")
+	}
+	dir := t.TempDir()
+	tests := []struct {
+		name, conf string
+		perm       os.FileMode
+		warn       bool
+	}{
+		{"privado con secreto", "[telegram]\ntoken = 123:ABC\n", 0600, false},
+		{"legible con secreto", "[telegram]\ntoken = 123:ABC\n", 0644, true},
+		{"legible con referencia", "[telegram]\ntoken = env:TELEGRAM_TOKEN\n", 0644, true},
+		{"legible sin secretos", "[mediacraft]\ndefault_profile = plex\n", 0644, true},
+		{"privado sin secretos", "[mediacraft]\ndefault_profile = plex\n", 0600, false},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("%d.conf", i))
+		if err := os.WriteFile(path, []byte(tt.conf), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, tt.perm); err != nil {
+			t.Fatal(err)
+		}
+		f, err := ini.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := checkSecretFile(path, f); (got != "") != tt.warn {
+			t.Errorf("%s: aviso %q, se esperaba aviso: %v", tt.name, got, tt.warn)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package decompress
 
 import (
+	"fmt"
+	"io"
 	"io/fs"
+	"mediacraft/i18n"
+	"mediacraft/platform"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,6 +16,11 @@ import (
 // DecompressAuto: descomprime cualquier archivo comprimido (zip, rar, 7z, tar, gz, etc.)
 // o multi-volumen, en una carpeta temporal. Devuelve los paths extraídos.
 func DecompressAuto(path string) ([]string, error) {
+	return DecompressAutoLog(path, nil)
+}
+
+// DecompressAutoLog es como DecompressAuto pero escribe en log la salida completa de 7z
+func DecompressAutoLog(path string, log io.Writer) ([]string, error) {
 	// Si es carpeta, buscar archivos comprimidos dentro
 	info, err := os.Stat(path)
 	if err != nil {
@@ -23,7 +32,7 @@ func DecompressAuto(path string) ([]string, error) {
 			if err != nil || d.IsDir() {
 				return nil
 			}
-			if isCompressed(p) {
+			if IsCompressed(p) {
 				filesToExtract = append(filesToExtract, p)
 			}
 			return nil
@@ -32,7 +41,7 @@ func DecompressAuto(path string) ([]string, error) {
 			return nil, err
 		}
 	} else {
-		if isCompressed(path) {
+		if IsCompressed(path) {
 			filesToExtract = append(filesToExtract, path)
 		}
 	}
@@ -42,11 +51,14 @@ func DecompressAuto(path string) ([]string, error) {
 	var allExtracted []string
 	for _, f := range filesToExtract {
 		joined, _ := JoinPartsIfNeeded(f)
-		tmpDir, err := os.MkdirTemp("", "mediacraft_unzip_")
+		if joined != f {
+			defer os.Remove(joined)
+		}
+		tmpDir, err := os.MkdirTemp("", tempPrefix)
 		if err != nil {
 			return nil, err
 		}
-		err = decompressWith7z(joined, tmpDir)
+		err = decompressWith7z(joined, tmpDir, log)
 		if err != nil {
 			return nil, err
 		}
@@ -61,16 +73,112 @@ func DecompressAuto(path string) ([]string, error) {
 	return allExtracted, nil
 }
 
+// Prefijo de las carpetas temporales donde se extraen los archivos
+const tempPrefix = "mediacraft_unzip_"
+
+// Cleanup elimina las carpetas temporales de extracción que contienen los paths
+// devueltos por DecompressAuto. Los paths que no están en una carpeta temporal
+// (p.ej. el archivo original) no se tocan.
+func Cleanup(extracted []string) {
+	tmp := filepath.Clean(os.TempDir())
+	removed := map[string]bool{}
+	for _, p := range extracted {
+		rel, err := filepath.Rel(tmp, p)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		top := strings.Split(filepath.ToSlash(rel), "/")[0]
+		if !strings.HasPrefix(top, tempPrefix) || removed[top] {
+			continue
+		}
+		removed[top] = true
+		os.RemoveAll(filepath.Join(tmp, top))
+	}
+}
+
+// TestArchive comprueba con 7z que el archivo (o el conjunto de volúmenes) está
+// completo y se puede leer
+func TestArchive(archive string) error {
+	sevenZip, err := platform.LookTool("7z")
+	if err != nil {
+		return err
+	}
+	return run7z(sevenZip, nil, "t", archive, "-y")
+}
+
+// ExtractTo extrae el archivo en la carpeta dest (que se crea si no existe), uniendo
+// antes sus volúmenes si está partido
+func ExtractTo(archive, dest string) error {
+	return ExtractToLog(archive, dest, nil)
+}
+
+// ExtractToLog es como ExtractTo pero escribe en log la salida completa de 7z
+func ExtractToLog(archive, dest string, log io.Writer) error {
+	if _, contiguous := ArchiveParts(archive); !contiguous {
+		return fmt.Errorf(i18n.T("%s: faltan volúmenes del archivo"), archive)
+	}
+	joined, err := JoinPartsIfNeeded(archive)
+	if err != nil {
+		return err
+	}
+	if joined != archive {
+		defer os.Remove(joined)
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	return decompressWith7z(joined, dest, log)
+}
+
 // decompressWith7z ejecuta 7z x archivo -o<destino>
-func decompressWith7z(archive, dest string) error {
-	cmd := exec.Command("7z", "x", archive, "-o"+dest, "-y")
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run()
+func decompressWith7z(archive, dest string, log io.Writer) error {
+	sevenZip, err := platform.LookTool("7z")
+	if err != nil {
+		return err
+	}
+	return run7z(sevenZip, log, "x", archive, "-o"+dest, "-y")
+}
+
+// run7z ejecuta 7z escribiendo su salida en log (si se indica). Si falla, el error
+// incluye el último mensaje de 7z (ERROR: Wrong password, Unexpected end of archive...).
+func run7z(sevenZip string, log io.Writer, args ...string) error {
+	var out strings.Builder
+	cmd := exec.Command(sevenZip, args...)
+	if log != nil {
+		fmt.Fprintf(log, "$ 7z %s\n", strings.Join(args, " "))
+		cmd.Stdout = io.MultiWriter(&out, log)
+	} else {
+		cmd.Stdout = &out
+	}
+	cmd.Stderr = cmd.Stdout
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if msg := lastMessage(out.String()); msg != "" {
+		return fmt.Errorf("7z: %s (%w)", msg, err)
+	}
+	return fmt.Errorf("7z: %w", err)
 }
 
-// isCompressed detecta si el archivo es comprimido o multi-volumen
-func isCompressed(path string) bool {
+// lastMessage devuelve la última línea de error de la salida de 7z, o la última no vacía
+func lastMessage(out string) string {
+	lines := strings.Split(strings.ReplaceAll(out, "\r", "\n"), "\n")
+	last := ""
+	for i := len(lines) - 1; i >= 0; i-- {
+		l := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(l, "ERROR") {
+			return l
+		}
+		if last == "" && l != "" {
+			last = l
+		}
+	}
+	return last
+}
+
+// IsCompressed detecta si el archivo es comprimido o multi-volumen
+func IsCompressed(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
 	compressed := []string{".zip", ".rar", ".7z", ".tar", ".gz", ".bz2", ".xz", ".lz", ".lzma", ".z01", ".001", ".part1", ".part01"}
 	for _, e := range compressed {
@@ -95,61 +203,43 @@ func atoiSafe(s string) int {
 	return n
 }
 
-// Detecta y une partes de archivos partidos (.001, .part01, .z01, etc.) en un archivo temporal único
-func JoinPartsIfNeeded(path string) (string, error) {
+// Patrones de volúmenes de archivos partidos: (prefijo, número de parte[, extensión])
+var partPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(.+)\.part0*([1-9][0-9]*)\.(rar|7z|zip|tar|gz|bz2|lz|lzma|xz)$`),
+	regexp.MustCompile(`(?i)(.+)\.0*([1-9][0-9]*)$`),
+	regexp.MustCompile(`(?i)(.+)\.z0*([1-9][0-9]*)$`),
+}
+
+// ArchiveParts devuelve todas las partes presentes del archivo partido al que
+// pertenece path, ordenadas por número de parte, y si la numeración es continua
+// desde la parte 1 (sin huecos). Si no es multi-volumen devuelve sólo path.
+func ArchiveParts(path string) ([]string, bool) {
 	dir := filepath.Dir(path)
 	base := filepath.Base(path)
 	// Detectar patrón de parte
-	patterns := []string{
-		`(?i)(.+)\.part0*([1-9][0-9]*)\.(rar|7z|zip|tar|gz|bz2|lz|lzma|xz)$`,
-		`(?i)(.+)\.0*([1-9][0-9]*)$`,
-		`(?i)(.+)\.z0*([1-9][0-9]*)$`,
-	}
-	var matches [][]string
-	var re *regexp.Regexp
-	for _, pat := range patterns {
-		re = regexp.MustCompile(pat)
-		if m := re.FindStringSubmatch(base); m != nil {
-			matches = append(matches, m)
+	var m []string
+	for _, re := range partPatterns {
+		if m = re.FindStringSubmatch(base); m != nil {
 			break
 		}
 	}
-	if len(matches) == 0 {
-		return path, nil // No es multi-volumen
+	if m == nil {
+		return []string{path}, true // No es multi-volumen
 	}
-	m := matches[0]
 	prefix := m[1]
-	ext := ""
-	if len(m) > 3 {
-		ext = "." + m[3]
-	}
 	// Buscar todas las partes
-	files, _ := os.ReadDir(dir)
-	var parts []string
-	for _, f := range files {
-		fname := f.Name()
-		for _, pat := range patterns {
-			re = regexp.MustCompile(pat)
-			if mm := re.FindStringSubmatch(fname); mm != nil && mm[1] == prefix {
-				parts = append(parts, fname)
-			}
-		}
-	}
-	if len(parts) <= 1 {
-		return path, nil // Solo una parte
-	}
-	// Ordenar partes por número
 	type partInfo struct {
 		name  string
 		index int
 	}
 	var partList []partInfo
-	for _, fname := range parts {
-		for _, pat := range patterns {
-			re = regexp.MustCompile(pat)
+	files, _ := os.ReadDir(dir)
+	for _, f := range files {
+		fname := f.Name()
+		for _, re := range partPatterns {
 			if mm := re.FindStringSubmatch(fname); mm != nil && mm[1] == prefix {
-				idx := atoiSafe(mm[2])
-				partList = append(partList, partInfo{fname, idx})
+				partList = append(partList, partInfo{fname, atoiSafe(mm[2])})
+				break
 			}
 		}
 	}
@@ -161,14 +251,38 @@ func JoinPartsIfNeeded(path string) (string, error) {
 			}
 		}
 	}
+	var parts []string
+	contiguous := true
+	for i, p := range partList {
+		if p.index != i+1 {
+			contiguous = false
+		}
+		parts = append(parts, filepath.Join(dir, p.name))
+	}
+	if len(parts) == 0 {
+		return []string{path}, true
+	}
+	return parts, contiguous
+}
+
+// Detecta y une partes de archivos partidos (.001, .part01, .z01, etc.) en un archivo temporal único
+func JoinPartsIfNeeded(path string) (string, error) {
+	parts, _ := ArchiveParts(path)
+	if len(parts) <= 1 {
+		return path, nil // Solo una parte
+	}
+	ext := ""
+	if m := partPatterns[0].FindStringSubmatch(filepath.Base(path)); m != nil {
+		ext = "." + m[3]
+	}
 	// Unir partes en archivo temporal
 	tmpFile, err := os.CreateTemp("", "mediacraft_joined_*"+ext)
 	if err != nil {
 		return path, err
 	}
 	defer tmpFile.Close()
-	for _, p := range partList {
-		f, err := os.Open(filepath.Join(dir, p.name))
+	for _, p := range parts {
+		f, err := os.Open(p)
 		if err != nil {
 			return path, err
 		}
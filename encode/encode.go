@@ -1,266 +1,596 @@
 package encode
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"math"
 	"mediacraft/config"
 	"mediacraft/decompress"
+	"mediacraft/history"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/platform"
+	"mediacraft/probe"
+	"mediacraft/progress"
+	"mediacraft/targets"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
 )
 
+var log = logging.For("encode")
+
+// Options agrupa las opciones de una conversión indicadas desde la línea de comandos
+type Options struct {
+	Profile string // Perfil o lista de perfiles (telegram,plex); vacío usa el sufijo @perfil o default_profile
+	Output  string // Plantilla, archivo o carpeta de salida (--output); vacío usa output_name
+	// Progress, si se indica, recibe el tiempo procesado y el porcentaje en lugar
+	// de mostrar las barras de progreso en consola
+	Progress func(current string, percent float64)
+	// Renderer, si se indica, muestra las barras de esta conversión junto a las del
+	// resto del lote; si no, la conversión usa uno propio
+	Renderer *progress.Renderer
+	// Log, si se indica, recibe la salida completa de ffmpeg
+	Log io.Writer
+	// Config es la configuración a usar; si es nil se carga la habitual (config.Load)
+	Config *config.Config
+	// DryRun muestra los comandos de ffmpeg que se ejecutarían, sin ejecutarlos ni
+	// registrar nada en el historial
+	DryRun bool
+	// OnOutput, si se indica, recibe la ruta de cada archivo de salida (también de
+	// los fallidos u omitidos), p.ej. para que watch no vuelva a procesarlos
+	OnOutput func(path string)
+}
+
 // Convert recibe el path y un perfil (por defecto: telegram)
-func Convert(path string) {
-	// Cargar perfiles desde el archivo INI
-	if err := config.LoadProfiles(); err != nil {
-		fmt.Println("[ERROR] No se pudieron cargar los perfiles:", err)
-		return
+func Convert(path string) error {
+	return ConvertWith(path, Options{})
+}
+
+// ConvertWith es como Convert pero con opciones adicionales
+func ConvertWith(path string, opts Options) error {
+	return ConvertContext(context.Background(), path, opts)
+}
+
+// ConvertContext es como ConvertWith pero se puede cancelar con ctx (se detiene ffmpeg).
+// Cada salida queda registrada en el historial.
+func ConvertContext(ctx context.Context, path string, opts Options) error {
+	cfg := opts.Config
+	if cfg == nil {
+		var err error
+		if cfg, err = config.Load(config.LoadOptions{}); err != nil {
+			log.Errorf("No se pudieron cargar los perfiles: %v", err)
+			return err
+		}
+	}
+	entries, err := convert(ctx, cfg, path, opts)
+	if opts.DryRun {
+		return err
+	}
+	for _, entry := range entries {
+		history.Record(cfg, entry)
+		emitResult(cfg, entry)
+		if opts.OnOutput != nil && entry.Output != "" {
+			opts.OnOutput(entry.Output)
+		}
+	}
+	return err
+}
+
+// emitResult emite los eventos del final de una salida (--output-format json)
+func emitResult(cfg *config.Config, e history.Entry) {
+	ev := logging.Event{Job: e.ID, Input: e.Inputs[0], Output: e.Output, Profile: e.Profile, Log: e.Log}
+	if e.Status == history.StatusFailed {
+		ev.Type, ev.Error = logging.EventError, cfg.Redact(e.Error)
+		logging.Emit(ev)
+		ev.Error = ""
+	}
+	ev.Type, ev.Status = logging.EventFinished, e.Status
+	logging.Emit(ev)
+}
+
+// selectProfiles determina los perfiles y el archivo real (soporta nombres con
+// espacios). Por orden de preferencia: opts.Profile, el sufijo @perfil, el
+// .mediacraft.conf de la carpeta y default_profile. Los dos primeros admiten una
+// lista separada por comas (telegram,plex,audio). auto indica que no se ha fijado
+// ninguno, así que lo pueden elegir las reglas de [reglas].
+func selectProfiles(cfg *config.Config, path, explicit string) (profiles []string, realPath string, auto bool, err error) {
+	profiles = []string{cfg.DefaultProfile}
+	realPath = path
+	auto = true
+	// Un .mediacraft.conf en la carpeta del archivo (o superiores) fija su perfil
+	if dirProfile, file := config.DirectoryProfile(filepath.Dir(path)); dirProfile != "" {
+		if cfg.HasProfile(dirProfile) {
+			profiles = []string{dirProfile}
+			auto = false
+			log.Infof("\033[36m  Perfil %s fijado por %s\033[0m\n", dirProfile, file)
+		} else {
+			log.Warnf("%s: perfil desconocido %s", file, dirProfile)
+		}
 	}
-	// Determinar perfil y archivo real (soporta nombres con espacios)
-	profile := config.DefaultProfile
-	realPath := path
 	at := lastAt(path)
 	if at != -1 && at != 0 && at != len(path)-1 {
-		filePart := path[:at]
-		profilePart := trimSpaces(path[at+1:])
-		if _, ok := config.Profiles[profilePart]; ok {
-			profile = profilePart
-			realPath = filePart
+		if names := splitProfiles(path[at+1:]); knownProfiles(cfg, names) {
+			profiles = names
+			realPath = path[:at]
+			auto = false
 		}
 	}
+	if explicit != "" {
+		names := splitProfiles(explicit)
+		for _, p := range names {
+			if !cfg.HasProfile(p) {
+				return profiles, realPath, false, fmt.Errorf(i18n.T("perfil desconocido: %s"), p)
+			}
+		}
+		if len(names) > 0 {
+			profiles = names
+			auto = false
+		}
+	}
+	return profiles, realPath, auto, nil
+}
+
+// ruleProfiles elige los perfiles con las reglas de [reglas] y muestra cuál coincide;
+// si no coincide ninguna, usa el perfil por defecto de las reglas o default_profile
+func ruleProfiles(cfg *config.Config, facts config.MediaFacts) []string {
+	if r, ok := cfg.MatchRule(facts); ok {
+		log.Infof("\033[36m  Regla %s (%s) → perfil %s\033[0m\n", r.Name, r.When(), r.Profile)
+		return splitProfiles(r.Profile)
+	}
+	profile := cfg.RulesDefault
+	if profile == "" {
+		profile = cfg.DefaultProfile
+	}
+	log.Infof("\033[36m  Ninguna regla coincide → perfil %s\033[0m\n", profile)
+	return splitProfiles(profile)
+}
+
+// MediaFacts reúne los datos del archivo con los que se evalúan las reglas de
+// [reglas]: su ruta, su tamaño en bytes y la duración y resolución según ffprobe
+func MediaFacts(media probe.Info, path string, size int64) config.MediaFacts {
+	// La ruta completa, para reglas como "ruta contiene /Musica/"
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	facts := config.MediaFacts{Path: path, Size: size, Duration: media.Format.Duration}
+	if v, ok := media.Video(); ok {
+		facts.Width, facts.Height = v.Width, v.Height
+	}
+	return facts
+}
+
+// convert realiza la conversión de path con uno o varios perfiles. La entrada se
+// descomprime y se analiza una sola vez, y las salidas que lo permiten se escriben en
+// una misma ejecución de ffmpeg. Devuelve las entradas del historial, una por salida.
+func convert(ctx context.Context, cfg *config.Config, path string, opts Options) ([]history.Entry, error) {
+	base := history.New("convert", path)
+	var jl *jobLog
+	fail := func(err error) ([]history.Entry, error) {
+		base.Log = jl.Path()
+		base.Finish(err)
+		return []history.Entry{base}, err
+	}
+	profiles, realPath, auto, err := selectProfiles(cfg, path, opts.Profile)
+	base.Profile = strings.Join(profiles, ",")
+	base.Inputs = []string{realPath}
+	if err != nil {
+		return fail(err)
+	}
+	// Registro del trabajo: los mensajes, los comandos y la salida completa de 7z y
+	// ffmpeg (en un ensayo no se ejecuta nada)
+	if !opts.DryRun {
+		jl = newJobLog(cfg, base, realPath)
+		defer jl.Close()
+	}
+	l := log.ForJob(base.ID).Tee(jl)
+	if !opts.DryRun {
+		logging.Emit(logging.Event{Type: logging.EventStarted, Job: base.ID, Input: realPath, Profile: base.Profile})
+	}
 
-	// Descomprimir si es necesario
+	// Descomprimir si es necesario (una sola vez para todos los perfiles)
 	inputName := realPath
 	if at := findSubstring(inputName, "@"); at != -1 {
 		inputName = inputName[:at]
 	}
-	extracted, err := decompress.DecompressAuto(inputName)
-	if err == nil && len(extracted) > 0 && (len(extracted) != 1 || extracted[0] != inputName) {
-		fmt.Printf("\033[33m  Archivo comprimido detectado y extraído a temporal: %s\033[0m\n", extracted[0])
+	if opts.DryRun && decompress.IsCompressed(inputName) {
+		l.Infof("\033[33m  (ensayo) %s es un archivo comprimido: se extraería antes de convertir\033[0m\n", inputName)
+		return nil, nil
+	}
+	extracted, err := decompress.DecompressAutoLog(inputName, jl)
+	if err != nil {
+		l.Errorf("No se pudo leer %s: %v%s", inputName, err, jl.hint())
+		return fail(err)
+	}
+	if len(extracted) > 0 && (len(extracted) != 1 || extracted[0] != inputName) {
+		l.Infof("\033[33m  Archivo comprimido detectado y extraído a temporal: %s\033[0m\n", extracted[0])
 		inputName = extracted[0]
+		base.Inputs = append(base.Inputs, inputName)
+	}
+	if info, err := os.Stat(inputName); err == nil && !info.IsDir() {
+		base.InputSize = info.Size()
+	}
+	// Analizar la entrada (también una sola vez)
+	var media probe.Info
+	if info, err := probe.Probe(inputName); err == nil {
+		media = *info
+	} else {
+		l.Errorf("No se pudo analizar %s con ffprobe: %v", fileNameWithExt(inputName), err)
+	}
+	totalDuration := media.Format.Duration
+	base.InputDuration = totalDuration
+	// Sin perfil indicado, lo eligen las reglas
+	if auto && (len(cfg.Rules) > 0 || cfg.RulesDefault != "") {
+		profiles = ruleProfiles(cfg, MediaFacts(media, realPath, base.InputSize))
+		base.Profile = strings.Join(profiles, ",")
 	}
 
-	// Determinar extensión de salida y formato ffmpeg (-f)
-	var outExt string
-	var ffFormat string
-	if ext, ok := config.ProfileExts[profile]; ok {
-		outExt = ext
-		if len(outExt) > 0 && outExt[0] != '.' {
-			outExt = "." + outExt
-		}
-		// Normalizar ffFormat según la extensión
-		switch strings.ToLower(outExt) {
-		case ".mkv":
-			ffFormat = "matroska"
-		case ".mp4":
-			ffFormat = "mp4"
-		case ".webm":
-			ffFormat = "webm"
-		default:
-			ffFormat = strings.TrimPrefix(strings.ToLower(outExt), ".")
+	// Preparar cada salida: archivo, pasadas y entrada del historial
+	var outs, active []*output
+	claimed := map[string]bool{}
+	for _, profile := range profiles {
+		o := &output{profile: profile, entry: base}
+		o.entry.Profile = profile
+		if len(outs) > 0 {
+			// Cada salida tiene su propia entrada en el historial
+			o.entry.ID = base.ID + "-" + profile
 		}
-	} else {
-		switch profile {
-		case "plex", "alta", "media", "baja":
-			outExt = ".mkv"
-			ffFormat = "matroska"
-		case "movil", "youtube":
-			outExt = ".mp4"
-			ffFormat = "mp4"
-		case "av1":
-			outExt = ".webm"
-			ffFormat = "webm"
-		default:
-			outExt = ".mp4"
-			ffFormat = "mp4"
-		}
-	}
-	// Determinar ruta de salida
-	outName := getOutputNameWithExt(realPath, outExt)
-	out := outName
-	if config.OutputDir != "" {
-		// Convertir OutputDir a ruta absoluta si es relativa
-		absOutputDir := config.OutputDir
-		if !filepath.IsAbs(config.OutputDir) {
-			cwd, _ := os.Getwd()
-			absOutputDir = filepath.Join(cwd, config.OutputDir)
-		}
-		if _, err := os.Stat(absOutputDir); os.IsNotExist(err) {
-			err = os.MkdirAll(absOutputDir, 0755)
-			if err != nil {
-			}
-		} else {
+		if err := planOutput(cfg, o, realPath, inputName, media, opts.Output, claimed); err != nil {
+			l.Errorf("No se pudo determinar el archivo de salida (%s): %v", profile, err)
+			o.err = err
+		} else if !o.skip {
+			active = append(active, o)
 		}
-		out = filepath.Join(absOutputDir, fileNameWithExt(outName))
+		outs = append(outs, o)
+	}
+	if len(active) > 0 {
+		// Con log_dir = salida, el registro va junto al primer archivo de salida
+		jl.place(filepath.Dir(active[0].out))
 	}
 	blue := "\033[34m"
 	green := "\033[32m"
 	yellow := "\033[33m"
 	reset := "\033[0m"
-	fmt.Printf("%sArchivo de salida final: %s%s\n", blue, out, reset)
-
-	// Mensajes previos profesionales (después de determinar perfil y extensión)
-	fmt.Printf("\n%s [SELECCIONADO] Perfil %s%s\n", yellow, capitalize(profile), reset)
-	if isNvidiaAvailable() {
-		fmt.Printf("%s GPU NVIDIA detectada - usando aceleración por hardware%s\n", blue, reset)
-	}
-	fmt.Printf("%s Iniciando conversión: %s%s\n", yellow, fileNameWithExt(inputName), reset)
-	fmt.Printf("%s Archivo de salida: %s%s\n", blue, fileNameWithExt(out), reset)
-
-	progressChan := make(chan string)
-	doneChan := make(chan struct{})
-	var lastProgress string
-	spinner := []rune{'⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'}
-	totalDuration := getDuration(inputName)
-	totalStr := formatDuration(totalDuration)
-	// --- Ejecutar ffmpeg según perfil ---
-	var argsLog1, argsLog2 []string
-	go func() {
-		i := 0
-		current := "00:00:00"
-		for {
-			select {
-			case t, ok := <-progressChan:
-				if ok {
-					lastProgress = t
-					current = t
+	if len(active) > 0 {
+		// Mensajes previos profesionales (después de determinar perfil y extensión)
+		if len(outs) == 1 {
+			l.Infof("\n%s [SELECCIONADO] Perfil %s%s\n", yellow, capitalize(profiles[0]), reset)
+		} else {
+			l.Infof("\n%s [SELECCIONADO] Perfiles %s%s\n", yellow, strings.Join(profiles, ", "), reset)
+		}
+		if NvidiaAvailable() {
+			l.Infof("%s GPU NVIDIA detectada - usando aceleración por hardware%s\n", blue, reset)
+		} else {
+			l.Infof("%s Sin GPU NVIDIA - se usarán codificadores por software%s\n", yellow, reset)
+		}
+		if !opts.DryRun {
+			l.Infof("%s Iniciando conversión: %s%s\n", yellow, fileNameWithExt(inputName), reset)
+			for _, o := range active {
+				l.Infof("%s Archivo de salida: %s%s\n", blue, fileNameWithExt(o.out), reset)
+			}
+		}
+	}
+	cleanup := usePassLogs(active)
+	defer cleanup()
+
+	// Las pasadas previas (la primera de dos) van por separado; las últimas de las
+	// salidas que leen la entrada igual se agrupan en una sola ejecución
+	var prelim []run
+	for _, o := range active {
+		for _, args := range o.passes[:len(o.passes)-1] {
+			prelim = append(prelim, run{outputs: []*output{o}, args: args})
+		}
+	}
+	if opts.DryRun {
+		if len(outs) == 1 {
+			for _, o := range active {
+				for i, args := range o.passes {
+					fmt.Fprintf(logging.Stdout, i18n.T("%s  Pasada %d:%s ffmpeg %s\n"), blue, i+1, reset, cfg.Redact(QuoteArgs(args)))
 				}
-			case <-doneChan:
-				fmt.Print("\r")
-				return
-			default:
-				fmt.Printf("\r%c Convirtiendo... [%s / %s]", spinner[i%len(spinner)], current, totalStr)
-				i++
-				slowSleep()
+			}
+			return nil, nil
+		}
+		for i, r := range append(prelim, finalRuns(active)...) {
+			what := fmt.Sprintf(i18n.T("pasada previa de %s"), r.names())
+			if r.final {
+				what = fmt.Sprintf(i18n.T("salidas %s"), r.names())
+			}
+			fmt.Fprintf(logging.Stdout, i18n.T("%s  Ejecución %d (%s):%s ffmpeg %s\n"), blue, i+1, what, reset, cfg.Redact(QuoteArgs(r.args)))
+		}
+		return nil, nil
+	}
+	for _, o := range active {
+		if err := os.MkdirAll(filepath.Dir(o.out), 0755); err != nil {
+			l.Errorf("No se pudo crear la carpeta de salida: %v", err)
+			o.err = err
+		}
+	}
+
+	// Una barra por salida: en la del lote (opts.Renderer) o en una propia. Con
+	// opts.Progress (la cola de trabajos) el progreso sólo va a esa función.
+	renderer := opts.Renderer
+	if renderer == nil && opts.Progress == nil {
+		renderer = progress.New(0)
+		renderer.Start()
+		defer renderer.Stop()
+	}
+	for _, o := range active {
+		if o.err == nil {
+			o.task = renderer.Add(realPath, o.profile, len(o.passes), totalDuration)
+		}
+	}
+	progressChan := make(chan ffProgress)
+	// Salidas de la ejecución en curso, que son las que reciben el progreso
+	var mu sync.Mutex
+	var running []*output
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for p := range progressChan {
+			mu.Lock()
+			for _, o := range running {
+				o.current = p.time
+				percent := percentOf(p.time, totalDuration)
+				o.task.Update(progress.Status{Pass: o.pass, Position: p.time, Percent: percent, FPS: p.fps, Speed: p.speed})
+				logging.Emit(logging.Event{Type: logging.EventProgress, Job: o.entry.ID, Input: realPath, Output: o.out, Profile: o.profile, Position: p.time, Percent: math.Round(percent*10) / 10, Pass: o.pass, FPS: p.fps, Speed: p.speed})
+			}
+			percent := overallPercent(active, totalDuration)
+			mu.Unlock()
+			if opts.Progress != nil {
+				opts.Progress(p.time, percent)
 			}
 		}
 	}()
 
-	switch profile {
-	case "telegram":
-		duration := getDuration(inputName)
-		videoBitrate := 2500.0
-		if duration > 0 {
-			targetBits := 3.5 * 1024 * 1024 * 1024 * 8
-			audioBitrate := 128000.0
-			videoBitrate = (targetBits/duration - audioBitrate) / 1000.0
-			if videoBitrate < 1000 {
-				videoBitrate = 1000
+	// El historial y los registros no deben contener secretos (jobLog ya los oculta)
+	logW := io.Writer(jl)
+	if opts.Log != nil {
+		logW = io.MultiWriter(jl, cfg.RedactWriter(opts.Log))
+	}
+	execute := func(r run) error {
+		logged := make([]string, len(r.args))
+		for i, a := range r.args {
+			logged[i] = cfg.Redact(a)
+		}
+		l.Debugf("ffmpeg %s", QuoteArgs(logged))
+		mu.Lock()
+		running = r.outputs
+		for _, o := range r.outputs {
+			o.entry.Args = append(o.entry.Args, logged)
+			o.pass++
+		}
+		mu.Unlock()
+		err := runFfmpegWithProgress(ctx, r.args, progressChan, logW)
+		mu.Lock()
+		running = nil
+		for _, o := range r.outputs {
+			o.err = err
+			o.done = err == nil && r.final
+		}
+		mu.Unlock()
+		return err
+	}
+	// Si una pasada falla no se ejecutan las siguientes de esa salida
+	for _, r := range prelim {
+		if r.outputs[0].err == nil && ctx.Err() == nil {
+			execute(r)
+		}
+	}
+	var ready []*output
+	for _, o := range active {
+		if o.err == nil {
+			ready = append(ready, o)
+		}
+	}
+	for _, r := range finalRuns(ready) {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := execute(r); err != nil && len(r.outputs) > 1 && ctx.Err() == nil {
+			// Por ejemplo, la GPU no admite tantas sesiones a la vez: cada salida por separado
+			l.Warnf("Falló la ejecución conjunta de %s; se repite cada salida por separado", r.names())
+			for _, o := range r.outputs {
+				if ctx.Err() == nil {
+					o.pass-- // Se repite la misma pasada
+					execute(run{outputs: []*output{o}, args: o.passes[len(o.passes)-1], final: true})
+				}
 			}
 		}
-		argsLog1 = []string{"-hwaccel", "cuda", "-i", inputName, "-c:v", "h264_nvenc", "-b:v", fmt.Sprintf("%dk", int(videoBitrate)), "-preset", "slow", "-c:a", "aac", "-b:a", "128k"}
-		if ffFormat != "" && out != "" {
-			argsLog1 = append(argsLog1, "-f", ffFormat)
-		}
-		argsLog1 = append(argsLog1, out)
-		runFfmpegWithProgress(argsLog1, progressChan)
-	case "plex":
-		argsLog1 = []string{"-y", "-hwaccel", "cuda", "-i", inputName, "-c:v", "hevc_nvenc", "-b:v", "5000k", "-preset", "slow", "-pass", "1", "-an", "-f", "null", "NUL"}
-		argsLog2 = []string{"-hwaccel", "cuda", "-i", inputName, "-c:v", "hevc_nvenc", "-b:v", "5000k", "-preset", "slow", "-pass", "2", "-c:a", "aac", "-b:a", "320k"}
-		if ffFormat != "" && out != "" {
-			argsLog2 = append(argsLog2, "-f", ffFormat)
-		}
-		argsLog2 = append(argsLog2, out)
-		runFfmpegWithProgress(argsLog1, progressChan)
-		runFfmpegWithProgress(argsLog2, progressChan)
-	case "alta", "media", "baja":
-		argsLog1 = []string{"-y", "-hwaccel", "cuda", "-i", inputName}
-		argsLog1 = append(argsLog1, config.Profiles[profile]...)
-		argsLog1 = append(argsLog1, "-pass", "1", "-an", "-f", "null", "NUL")
-		argsLog2 = []string{"-hwaccel", "cuda", "-i", inputName}
-		argsLog2 = append(argsLog2, config.Profiles[profile]...)
-		argsLog2 = append(argsLog2, "-pass", "2")
-		if ffFormat != "" && out != "" {
-			argsLog2 = append(argsLog2, "-f", ffFormat)
-		}
-		argsLog2 = append(argsLog2, out)
-		runFfmpegWithProgress(argsLog1, progressChan)
-		runFfmpegWithProgress(argsLog2, progressChan)
-	case "movil", "youtube":
-		argsLog1 = []string{"-hwaccel", "cuda", "-i", inputName, "-c:v", "h264_nvenc"}
-		if len(config.Profiles[profile]) > 2 {
-			argsLog1 = append(argsLog1, config.Profiles[profile][2:]...)
-		}
-		if ffFormat != "" && out != "" {
-			argsLog1 = append(argsLog1, "-f", ffFormat)
-		}
-		argsLog1 = append(argsLog1, out)
-		runFfmpegWithProgress(argsLog1, progressChan)
-	case "av1":
-		argsLog1 = []string{"-y", "-i", inputName, "-c:v", "libaom-av1", "-crf", "30", "-b:v", "0", "-pass", "1", "-an", "-f", "null", "NUL"}
-		argsLog2 = []string{"-i", inputName, "-c:v", "libaom-av1", "-crf", "30", "-b:v", "0", "-pass", "2", "-c:a", "libopus", "-b:a", "128k"}
-		if ffFormat != "" && out != "" {
-			argsLog2 = append(argsLog2, "-f", ffFormat)
-		}
-		argsLog2 = append(argsLog2, out)
-		runFfmpegWithProgress(argsLog1, progressChan)
-		runFfmpegWithProgress(argsLog2, progressChan)
-	default:
-		argsLog1 = []string{"-i", inputName}
-		argsLog1 = append(argsLog1, config.Profiles[profile]...)
-		if ffFormat != "" && out != "" {
-			argsLog1 = append(argsLog1, "-f", ffFormat)
-		}
-		argsLog1 = append(argsLog1, out)
-		runFfmpegWithProgress(argsLog1, progressChan)
-	}
-	close(doneChan)
-	// Mostrar resumen final limpio
-	info, err := os.Stat(out)
-	var durOut float64
-	if err == nil && info.Size() > 0 {
-		durOut = getDuration(out)
+	}
+	close(progressChan)
+	<-stopped
+	canceled := ctx.Err() != nil
+	if canceled {
+		l.Infof("\033[33m  Conversión cancelada: %s\033[0m\n", fileNameWithExt(inputName))
+	}
+
+	// Resultado de cada salida
+	var entries []history.Entry
+	var errs, resumenes []string
+	var firstErr error
+	for _, o := range outs {
+		switch {
+		case o.skip:
+		case canceled && !o.done:
+			o.err = ctx.Err()
+		case o.err != nil:
+			label := fileNameWithExt(inputName)
+			if len(outs) > 1 {
+				label += " (" + o.profile + ")"
+			}
+			l.Errorf("La conversión de %s falló: %s%s", label, cfg.Redact(o.err.Error()), jl.hint())
+		default:
+			// Comprobar la salida y mostrar resumen final limpio
+			durOut, problem := verifyOutput(o.out, totalDuration)
+			if problem != "" {
+				l.Warnf("%s: %s", fileNameWithExt(o.out), problem)
+			}
+			o.entry.OutputDuration = durOut
+			resumen := fmt.Sprintf(i18n.T("Resumen: %s → %s | Perfil: %s | Duración salida: %s | Progreso final: %s"), fileNameWithExt(inputName), fileNameWithExt(o.out), o.profile, formatDuration(durOut), o.current)
+			l.Infof("%s%s%s\n", green, resumen, reset)
+			resumenes = append(resumenes, resumen)
+		}
+		if o.err != nil && !canceled {
+			errs = append(errs, o.profile+": "+o.err.Error())
+			if firstErr == nil {
+				firstErr = o.err
+			}
+		}
+		o.task.Done(o.err)
+		o.entry.Log = jl.Path()
+		o.entry.Finish(o.err)
+		entries = append(entries, o.entry)
+	}
+	// Notificación Telegram si está habilitado (una sola para todas las salidas)
+	if len(resumenes) > 0 && cfg.EnableNotifications && cfg.TelegramToken != "" && cfg.TelegramChatID != "" {
+		go sendTelegramNotification(cfg, strings.Join(resumenes, "\n"))
+	}
+	switch {
+	case canceled:
+		return entries, ctx.Err()
+	case len(errs) == 0:
+		return entries, nil
+	case len(outs) == 1:
+		return entries, fmt.Errorf("%w%s", firstErr, jl.hint())
+	}
+	return entries, fmt.Errorf(i18n.T("fallaron %d de %d salidas: %s%s"), len(errs), len(outs), strings.Join(errs, "; "), jl.hint())
+}
+
+// planOutput decide el archivo de salida y las pasadas de ffmpeg de una salida.
+// claimed guarda las salidas ya asignadas en esta conversión para que dos perfiles
+// no escriban en el mismo archivo.
+func planOutput(cfg *config.Config, o *output, realPath, inputName string, media probe.Info, override string, claimed map[string]bool) error {
+	profile := o.profile
+	// Determinar extensión de salida y formato ffmpeg (-f)
+	outExt, ffFormat := profileFormat(cfg, profile)
+	// La extensión de --output archivo.ext decide el contenedor; si no, el destino de
+	// reproducción (target) puede obligar a cambiarlo
+	target, hasTarget := targets.Get(cfg.ProfileOption(profile, "target", ""))
+	if ext := overrideExt(override); ext != "" {
+		if !strings.EqualFold(ext, outExt) {
+			log.Infof("\033[33m  --output: se usa el contenedor %s en lugar del del perfil (%s)\033[0m\n", ext, outExt)
+			outExt, ffFormat = ext, formatForExt(ext)
+		}
+		if hasTarget && !target.SupportsContainer(outExt) {
+			log.Warnf("Destino %s: el contenedor %s no es compatible", target.Name, outExt)
+		}
+	} else if hasTarget && !target.SupportsContainer(outExt) {
+		log.Infof("\033[33m  Destino %s: el contenedor %s no es compatible, se usa %s\033[0m\n", target.Name, outExt, target.Containers[0])
+		outExt = target.Containers[0]
+		ffFormat = formatForExt(outExt)
+	}
+	// Determinar ruta de salida (plantilla output_name, --output y política de colisión)
+	out, err := outputPath(cfg, realPath, inputName, profile, outExt, media, override)
+	if err != nil {
+		return err
+	}
+	if claimed[out] {
+		ext := filepath.Ext(out)
+		renamed := strings.TrimSuffix(out, ext) + " [" + profile + "]" + ext
+		log.Infof("\033[33m  %s ya es la salida de otro perfil; %s usa %s\033[0m\n", fileNameWithExt(out), profile, fileNameWithExt(renamed))
+		out = renamed
+	}
+	out, o.skip = resolveCollision(out, cfg.CollisionPolicy)
+	claimed[out] = true
+	o.out = out
+	o.entry.Output = out
+	if o.skip {
+		log.Infof("\033[33m  El archivo de salida ya existe, se omite: %s\033[0m\n", out)
+		o.entry.Status = history.StatusSkipped
+		return nil
+	}
+	log.Infof("\033[34mArchivo de salida final: %s\033[0m\n", out)
+
+	// Pistas, capítulos, adjuntos y metadatos del contenedor
+	metaIn, metaOut := streamArgs(cfg, media, realPath, profile, outExt)
+	metaOut = append(metaOut, metadataArgs(cfg, media, inputName, realPath, profile, outExt)...)
+	// --- Pasadas de ffmpeg según perfil ---
+	passes := buildPasses(cfg, profile, inputName, metaIn, metaOut, ffFormat, out)
+	if !NvidiaAvailable() {
+		passes = softwareFallback(passes)
+	}
+	// Copiar pistas que ya cumplen el perfil en lugar de recodificarlas
+	if hasTarget {
+		passes = applyTarget(target, media, passes, outExt)
 	} else {
-		durOut = 0
+		passes = planRemux(cfg, media, passes, profile)
+	}
+	o.passes = passes
+	return nil
+}
+
+// profileFormat devuelve la extensión de salida y el formato de ffmpeg (-f) del perfil
+// (MP4 si no indica ext)
+func profileFormat(cfg *config.Config, profile string) (outExt, ffFormat string) {
+	outExt = cfg.Profiles[profile].Ext
+	if outExt == "" {
+		outExt = "mp4"
 	}
-	resumen := fmt.Sprintf("Resumen: %s → %s | Perfil: %s | Duración salida: %s | Progreso final: %s", fileNameWithExt(inputName), fileNameWithExt(out), profile, formatDuration(durOut), lastProgress)
-	fmt.Printf("%s%s%s\n", green, resumen, reset)
-	// Notificación Telegram si está habilitado
-	if config.EnableNotifications && config.TelegramToken != "" && config.TelegramChatID != "" {
-		go sendTelegramNotification(config.TelegramToken, config.TelegramChatID, resumen)
+	if outExt[0] != '.' {
+		outExt = "." + outExt
 	}
+	return outExt, formatForExt(outExt)
+}
+
+// buildPasses construye los argumentos de ffmpeg de cada pasada a partir del perfil
+// compilado. Con pasadas = 2 la primera sólo analiza el vídeo y no escribe nada. La
+// última pasada es la que escribe el archivo de salida.
+func buildPasses(cfg *config.Config, profile, inputName string, metaIn, metaOut []string, ffFormat, out string) [][]string {
+	p := cfg.Profiles[profile]
+	final := append(inputArgs(cfg, profile, inputName), p.OutputArgs()...)
+	if cfg.ProfileOption(profile, "pasadas", "1") != "2" {
+		return [][]string{finishArgs(final, metaIn, metaOut, ffFormat, out)}
+	}
+	first := append([]string{"-y"}, inputArgs(cfg, profile, inputName)...)
+	first = append(first, p.Video...)
+	first = append(first, p.Output...)
+	first = append(first, "-pass", "1", "-an", "-f", "null", platform.NullDevice())
+	final = append(final, "-pass", "2")
+	return [][]string{first, finishArgs(final, metaIn, metaOut, ffFormat, out)}
+}
+
+// inputArgs devuelve las opciones de entrada del perfil seguidas de -i y el archivo
+// de entrada
+func inputArgs(cfg *config.Config, profile string, inputName string) []string {
+	return append(append([]string{}, cfg.Profiles[profile].Input...), "-i", inputName)
 }
 
 // Envía una notificación a Telegram usando el bot y chat_id configurados
-func sendTelegramNotification(token, chatID, message string) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", token)
+func sendTelegramNotification(cfg *config.Config, message string) {
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", cfg.TelegramToken)
 	data := url.Values{}
-	data.Set("chat_id", chatID)
+	data.Set("chat_id", cfg.TelegramChatID)
 	data.Set("text", message)
 	data.Set("disable_web_page_preview", "true")
 	data.Set("parse_mode", "HTML")
-	_, _ = http.Post(apiURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	resp, err := http.Post(apiURL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	if err != nil {
+		// El error incluye la URL, que lleva el token
+		log.Warnf("No se pudo enviar la notificación de Telegram: %s", cfg.Redact(err.Error()))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Warnf("Telegram rechazó la notificación: %s", resp.Status)
+	}
 }
 
-// getOutputNameWithExt genera el nombre de salida limpio con la extensión deseada
-// getOutputNameWithExt genera el nombre de salida limpio con la extensión deseada
-func getOutputNameWithExt(input string, ext string) string {
-	// Quitar ruta
-	name := input
-	for i := len(name) - 1; i >= 0; i-- {
-		if name[i] == '/' || name[i] == '\\' {
-			name = name[i+1:]
-			break
+// QuoteArgs une los argumentos para mostrarlos, entrecomillando los que tienen espacios
+func QuoteArgs(args []string) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\"'") {
+			a = `"` + strings.ReplaceAll(a, `"`, `\"`) + `"`
 		}
+		parts[i] = a
 	}
-	// Quitar @perfil si lo hubiera
-	if at := findSubstring(name, "@"); at != -1 {
-		name = name[:at]
-	}
-	// Quitar extensión existente
-	if dot := findSubstring(name, "."); dot != -1 {
-		name = name[:dot]
-	}
-	// Si el nombre queda vacío, usar 'output'
-	if len(name) == 0 {
-		name = "output"
-	}
-	outputName := name + ext
-	return outputName
+	return strings.Join(parts, " ")
 }
 
 // fileNameWithExt devuelve el nombre de archivo con extensión, sin ruta
@@ -276,6 +606,18 @@ func fileNameWithExt(path string) string {
 	return name
 }
 
+// formatForExt devuelve el formato de ffmpeg (-f) correspondiente a una extensión
+func formatForExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".mkv":
+		return "matroska"
+	case ".m4v":
+		return "mp4"
+	default:
+		return strings.TrimPrefix(strings.ToLower(ext), ".")
+	}
+}
+
 // capitalize la primera letra
 func capitalize(s string) string {
 	if len(s) == 0 {
@@ -284,12 +626,6 @@ func capitalize(s string) string {
 	return string([]rune(s)[0]-32) + s[1:]
 }
 
-// isNvidiaAvailable simula detección de GPU NVIDIA
-func isNvidiaAvailable() bool {
-	// Simulación: siempre true para demo
-	return true
-}
-
 // cleanFileName limpia el nombre para mostrar bonito
 func cleanFileName(path string) string {
 	// Quita ruta y extensión
@@ -308,14 +644,24 @@ func cleanFileName(path string) string {
 	return name
 }
 
-// Ejecuta ffmpeg y envía el progreso por canal
-func runFfmpegWithProgress(args []string, progressChan chan<- string) {
-	cmd := exec.Command("ffmpeg", args...)
-	stderr, _ := cmd.StderrPipe()
+// Ejecuta ffmpeg y envía el progreso por canal. La salida completa va a logw; si
+// ffmpeg falla, el error incluye su último mensaje (el motivo del fallo).
+func runFfmpegWithProgress(ctx context.Context, args []string, progressChan chan<- ffProgress, logw io.Writer) error {
+	ffmpeg, err := platform.LookTool("ffmpeg")
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, ffmpeg, args...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
 	cmd.Stdout = cmd.Stderr
-	_ = cmd.Start()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
 	buf := make([]byte, 4096)
-	var line string
+	var line, last string
 	for {
 		n, err := stderr.Read(buf)
 		if n > 0 {
@@ -327,8 +673,13 @@ func runFfmpegWithProgress(args []string, progressChan chan<- string) {
 				}
 				l := line[:idx]
 				line = line[idx+1:]
+				if logw != nil && l != "" {
+					fmt.Fprintln(logw, l)
+				}
 				if t := extractTime(l); t != "" {
-					progressChan <- t
+					progressChan <- ffProgress{time: t, fps: extractNumber(l, "fps="), speed: extractNumber(l, "speed=")}
+				} else if isFfmpegMessage(l) {
+					last = trimSpaces(l)
 				}
 			}
 		}
@@ -336,7 +687,28 @@ func runFfmpegWithProgress(args []string, progressChan chan<- string) {
 			break
 		}
 	}
-	_ = cmd.Wait()
+	if err := cmd.Wait(); err != nil {
+		if last != "" && ctx.Err() == nil {
+			return fmt.Errorf("ffmpeg: %s (%w)", last, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// isFfmpegMessage indica si la línea de ffmpeg puede explicar un fallo: no está
+// vacía y no es el resumen final ("Conversion failed!") ni una línea de estadísticas
+func isFfmpegMessage(l string) bool {
+	l = trimSpaces(l)
+	if l == "" || l == "Conversion failed!" || strings.HasPrefix(l, "Exiting normally") || strings.Contains(l, "muxing overhead") {
+		return false
+	}
+	for _, p := range []string{"video:", "frame=", "size="} {
+		if strings.HasPrefix(l, p) {
+			return false
+		}
+	}
+	return true
 }
 
 // Extrae el valor de time= de una línea de ffmpeg
@@ -353,6 +725,49 @@ func extractTime(line string) string {
 	return ""
 }
 
+// ffProgress es una línea de estadísticas de ffmpeg
+type ffProgress struct {
+	time  string
+	fps   float64
+	speed float64 // Respecto al tiempo real ("speed=2.5x")
+}
+
+// extractNumber extrae el número que sigue a key (p.ej. "fps=") en una línea de
+// ffmpeg, o 0 si no está o no es un número ("speed=N/A")
+func extractNumber(line, key string) float64 {
+	idx := findSubstring(line, key)
+	if idx == -1 {
+		return 0
+	}
+	t := trimSpaces(line[idx+len(key):])
+	end := 0
+	for end < len(t) && (t[end] >= '0' && t[end] <= '9' || t[end] == '.') {
+		end++
+	}
+	n, err := strconv.ParseFloat(t[:end], 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// percentOf calcula el porcentaje de un tiempo HH:MM:SS.ms respecto a la duración total
+func percentOf(t string, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	var h, m int
+	var sec float64
+	if _, err := fmt.Sscanf(t, "%d:%d:%f", &h, &m, &sec); err != nil {
+		return 0
+	}
+	p := (float64(h*3600+m*60) + sec) * 100 / total
+	if p > 100 {
+		p = 100
+	}
+	return p
+}
+
 // Busca el final de línea (\n o \r)
 func findLineEnd(s string) int {
 	for i, c := range s {
@@ -381,21 +796,27 @@ func formatDuration(d float64) string {
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
 
-// getDuration obtiene la duración en segundos usando ffprobe
-func getDuration(path string) float64 {
-	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", path).Output()
+// verifyOutput analiza el archivo escrito y devuelve su duración y, si algo no
+// cuadra (no se puede leer, no tiene pistas o dura bastante menos o más que la
+// entrada), la descripción del problema
+func verifyOutput(out string, inputDuration float64) (float64, string) {
+	info, err := probe.Probe(out)
 	if err != nil {
-		fmt.Printf("\033[31m[ERROR] No se pudo obtener la duración con ffprobe: %v\033[0m\n", err)
-		return 0
+		return 0, fmt.Sprintf(i18n.T("no se pudo comprobar la salida: %v"), err)
 	}
-	var dur float64
-	fmt.Sscanf(string(out), "%f", &dur)
-	return dur
-}
-
-// slowSleep espera 1.5 segundos
-func slowSleep() {
-	time.Sleep(100 * time.Millisecond)
+	if len(info.Streams) == 0 {
+		return 0, i18n.T("la salida no tiene pistas")
+	}
+	dur := info.Format.Duration
+	// Margen: 2 segundos o el 1 % de la duración
+	margin := inputDuration / 100
+	if margin < 2 {
+		margin = 2
+	}
+	if inputDuration > 0 && dur > 0 && (dur < inputDuration-margin || dur > inputDuration+margin) {
+		return dur, fmt.Sprintf(i18n.T("la salida dura %s y la entrada %s"), formatDuration(dur), formatDuration(inputDuration))
+	}
+	return dur, ""
 }
 
 // trimSpaces elimina espacios en blanco al inicio y final
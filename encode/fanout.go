@@ -0,0 +1,191 @@
+package encode
+
+import (
+	"mediacraft/config"
+	"mediacraft/history"
+	"mediacraft/platform"
+	"mediacraft/progress"
+	"strings"
+)
+
+// maxNvencPerRun limita los codificadores NVENC de una misma ejecución de ffmpeg: las
+// GPU GeForce sólo admiten unas pocas sesiones de codificación simultáneas
+const maxNvencPerRun = 3
+
+// output es una de las salidas de una conversión con varios perfiles
+type output struct {
+	profile string
+	out     string
+	passes  [][]string
+	entry   history.Entry
+	skip    bool   // Ya existe y la política de colisión la omite
+	current string // Último tiempo procesado
+	pass    int    // Pasada en curso, empezando por 1
+	task    *progress.Task
+	done    bool
+	err     error
+}
+
+// run es una ejecución de ffmpeg y las salidas que escribe
+type run struct {
+	outputs []*output
+	args    []string
+	final   bool // Es la última pasada de sus salidas
+}
+
+// names devuelve los perfiles de las salidas de la ejecución
+func (r run) names() string {
+	names := make([]string, len(r.outputs))
+	for i, o := range r.outputs {
+		names[i] = o.profile
+	}
+	return strings.Join(names, ", ")
+}
+
+// splitProfiles separa una lista de perfiles (telegram,plex,audio) sin repetidos
+func splitProfiles(list string) []string {
+	var res []string
+	for _, p := range strings.Split(list, ",") {
+		if p = trimSpaces(p); p != "" && !contains(res, p) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// knownProfiles indica si todos los perfiles de la lista existen
+func knownProfiles(cfg *config.Config, profiles []string) bool {
+	for _, p := range profiles {
+		if !cfg.HasProfile(p) {
+			return false
+		}
+	}
+	return len(profiles) > 0
+}
+
+// inputEnd devuelve dónde empiezan las opciones de salida: tras el último -i
+func inputEnd(args []string) int {
+	end := 0
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-i" {
+			end = i + 2
+		}
+	}
+	return end
+}
+
+// inputKey identifica cómo se leen las entradas de una pasada. Las salidas con la
+// misma clave pueden compartir una sola lectura y decodificación. Si withoutHW, no se
+// tienen en cuenta las opciones de aceleración por hardware (para salidas sin vídeo).
+func inputKey(args []string, withoutHW bool) string {
+	var key []string
+	in := args[:inputEnd(args)]
+	for i := 0; i < len(in); i++ {
+		if withoutHW && strings.HasPrefix(in[i], "-hwaccel") && i+1 < len(in) {
+			i++
+			continue
+		}
+		key = append(key, in[i])
+	}
+	return strings.Join(key, "\x00")
+}
+
+// audioOnly indica si la pasada no escribe vídeo
+func audioOnly(args []string) bool {
+	return contains(args[inputEnd(args):], "-vn")
+}
+
+// nvencCount cuenta los codificadores NVENC de las opciones de salida
+func nvencCount(args []string) int {
+	n := 0
+	out := args[inputEnd(args):]
+	for i := 0; i+1 < len(out); i++ {
+		if strings.HasPrefix(out[i], "-c:v") && strings.HasSuffix(out[i+1], "_nvenc") {
+			n++
+		}
+	}
+	return n
+}
+
+// finalRuns agrupa la última pasada de las salidas en el menor número de ejecuciones
+// de ffmpeg. Las que leen la entrada igual comparten una sola lectura y decodificación:
+// ffmpeg -i entrada [opciones 1] salida1 [opciones 2] salida2... Las salidas sin vídeo
+// se unen a cualquier grupo aunque éste use aceleración por hardware.
+func finalRuns(outs []*output) []run {
+	type group struct {
+		run
+		key, keyNoHW string
+		nvenc        int
+	}
+	var groups []*group
+	add := func(o *output) {
+		args := o.passes[len(o.passes)-1]
+		key, keyNoHW := inputKey(args, false), inputKey(args, true)
+		audio := audioOnly(args)
+		n := nvencCount(args)
+		for _, g := range groups {
+			if (g.key == key || audio && g.keyNoHW == keyNoHW) && g.nvenc+n <= maxNvencPerRun {
+				g.outputs = append(g.outputs, o)
+				g.args = append(g.args, args[inputEnd(args):]...)
+				g.nvenc += n
+				return
+			}
+		}
+		g := &group{run: run{outputs: []*output{o}, args: append([]string{}, args...), final: true}, key: key, keyNoHW: keyNoHW, nvenc: n}
+		groups = append(groups, g)
+	}
+	// Primero las salidas con vídeo, que fijan cómo se lee la entrada
+	for _, o := range outs {
+		if !audioOnly(o.passes[len(o.passes)-1]) {
+			add(o)
+		}
+	}
+	for _, o := range outs {
+		if audioOnly(o.passes[len(o.passes)-1]) {
+			add(o)
+		}
+	}
+	runs := make([]run, len(groups))
+	for i, g := range groups {
+		runs[i] = g.run
+	}
+	return runs
+}
+
+// usePassLogs da a cada salida de dos pasadas su propio archivo de estadísticas en
+// la carpeta temporal (platform.PassLog), para que las primeras pasadas de varios
+// perfiles o de trabajos simultáneos no se pisen. Devuelve una función que los borra.
+func usePassLogs(outs []*output) func() {
+	var prefixes []string
+	for _, o := range outs {
+		if len(o.passes) < 2 {
+			continue
+		}
+		prefix := platform.PassLog(o.entry.ID)
+		for i, args := range o.passes {
+			o.passes[i] = setOutputOptions(args, []string{"-passlogfile", prefix})
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return func() {
+		for _, p := range prefixes {
+			platform.RemovePassLog(p)
+		}
+	}
+}
+
+// overallPercent es la media del progreso de las salidas
+func overallPercent(outs []*output, total float64) float64 {
+	if len(outs) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, o := range outs {
+		if o.done {
+			sum += 100
+		} else {
+			sum += percentOf(o.current, total)
+		}
+	}
+	return sum / float64(len(outs))
+}
@@ -0,0 +1,134 @@
+package encode
+
+import (
+	"mediacraft/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testOutput devuelve una salida de una sola pasada
+func testOutput(profile string, args ...string) *output {
+	return &output{profile: profile, passes: [][]string{args}}
+}
+
+func TestFinalRuns(t *testing.T) {
+	cuda := []string{"-hwaccel", "cuda", "-i", "in.mkv"}
+	soft := []string{"-i", "in.mkv"}
+	with := func(in []string, out ...string) []string { return append(append([]string{}, in...), out...) }
+	tests := []struct {
+		name string
+		outs []*output
+		want [][]string // Perfiles de cada ejecución
+		args []string   // Argumentos de la primera ejecución (si no es nil)
+	}{
+		{
+			name: "misma entrada, una ejecución",
+			outs: []*output{
+				testOutput("telegram", with(cuda, "-c:v", "h264_nvenc", "a.mp4")...),
+				testOutput("plex", with(cuda, "-c:v", "hevc_nvenc", "b.mkv")...),
+			},
+			want: [][]string{{"telegram", "plex"}},
+			args: with(cuda, "-c:v", "h264_nvenc", "a.mp4", "-c:v", "hevc_nvenc", "b.mkv"),
+		},
+		{
+			name: "entradas distintas",
+			outs: []*output{
+				testOutput("telegram", with(cuda, "-c:v", "h264_nvenc", "a.mp4")...),
+				testOutput("av1", with(soft, "-c:v", "libsvtav1", "b.webm")...),
+			},
+			want: [][]string{{"telegram"}, {"av1"}},
+		},
+		{
+			// Las salidas sin vídeo se unen aunque el grupo use aceleración, y van detrás
+			name: "audio con vídeo acelerado",
+			outs: []*output{
+				testOutput("audio", with(soft, "-vn", "-c:a", "libmp3lame", "a.mp3")...),
+				testOutput("telegram", with(cuda, "-c:v", "h264_nvenc", "b.mp4")...),
+			},
+			want: [][]string{{"telegram", "audio"}},
+			args: with(cuda, "-c:v", "h264_nvenc", "b.mp4", "-vn", "-c:a", "libmp3lame", "a.mp3"),
+		},
+		{
+			name: "límite de NVENC por ejecución",
+			outs: []*output{
+				testOutput("a", with(cuda, "-c:v", "h264_nvenc", "a.mp4")...),
+				testOutput("b", with(cuda, "-c:v", "h264_nvenc", "b.mp4")...),
+				testOutput("c", with(cuda, "-c:v", "hevc_nvenc", "c.mkv")...),
+				testOutput("d", with(cuda, "-c:v", "hevc_nvenc", "d.mkv")...),
+				testOutput("e", with(cuda, "-c:v", "libx264", "e.mp4")...),
+			},
+			want: [][]string{{"a", "b", "c", "e"}, {"d"}},
+		},
+	}
+	for _, tt := range tests {
+		runs := finalRuns(tt.outs)
+		var got [][]string
+		for _, r := range runs {
+			var names []string
+			for _, o := range r.outputs {
+				names = append(names, o.profile)
+			}
+			got = append(got, names)
+			if !r.final {
+				t.Errorf("%s: ejecución %q no marcada como final", tt.name, names)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ejecuciones %q, se esperaba %q", tt.name, got, tt.want)
+			continue
+		}
+		if tt.args != nil && !reflect.DeepEqual(runs[0].args, tt.args) {
+			t.Errorf("%s: argumentos %q, se esperaba %q", tt.name, runs[0].args, tt.args)
+		}
+	}
+}
+
+func TestSelectProfiles(t *testing.T) {
+	dir := t.TempDir()
+	serie := filepath.Join(dir, "serie")
+	if err := os.Mkdir(serie, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(serie, ".mediacraft.conf"), []byte("perfil = plex\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{DefaultProfile: "telegram", Profiles: map[string]config.Profile{
+		"telegram": {}, "plex": {}, "audio": {},
+	}}
+	in := filepath.Join(dir, "a.mkv")
+	inSerie := filepath.Join(serie, "a.mkv")
+	tests := []struct {
+		path, explicit string
+		profiles       []string
+		realPath       string
+		auto           bool
+	}{
+		{in, "", []string{"telegram"}, in, true},
+		{in + "@plex,audio", "", []string{"plex", "audio"}, in, false},
+		{in + "@plex, plex", "", []string{"plex"}, in, false},
+		// Un @ que no es una lista de perfiles es parte del nombre
+		{filepath.Join(dir, "a@b.mkv"), "", []string{"telegram"}, filepath.Join(dir, "a@b.mkv"), true},
+		{in + "@plex,nevera", "", []string{"telegram"}, in + "@plex,nevera", true},
+		{in + "@plex", "audio, telegram", []string{"audio", "telegram"}, in, false},
+		// .mediacraft.conf de la carpeta, por debajo de @perfil y del explícito
+		{inSerie, "", []string{"plex"}, inSerie, false},
+		{inSerie + "@audio", "", []string{"audio"}, inSerie, false},
+		{inSerie, "telegram", []string{"telegram"}, inSerie, false},
+	}
+	for _, tt := range tests {
+		profiles, realPath, auto, err := selectProfiles(cfg, tt.path, tt.explicit)
+		if err != nil {
+			t.Errorf("selectProfiles(%q, %q): error inesperado: %v", tt.path, tt.explicit, err)
+			continue
+		}
+		if !reflect.DeepEqual(profiles, tt.profiles) || realPath != tt.realPath || auto != tt.auto {
+			t.Errorf("selectProfiles(%q, %q) = %q, %q, %v; se esperaba %q, %q, %v",
+				tt.path, tt.explicit, profiles, realPath, auto, tt.profiles, tt.realPath, tt.auto)
+		}
+	}
+	if _, _, _, err := selectProfiles(cfg, in, "plex,nevera"); err == nil {
+		t.Errorf("selectProfiles con un perfil desconocido: se esperaba un error")
+	}
+}
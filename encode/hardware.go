@@ -0,0 +1,64 @@
+package encode
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Equivalente por software de cada codificador NVENC
+var softwareEquivalents = map[string]string{
+	"h264_nvenc": "libx264", "hevc_nvenc": "libx265", "av1_nvenc": "libsvtav1",
+}
+
+var (
+	nvidiaOnce      sync.Once
+	nvidiaAvailable bool
+)
+
+// NvidiaAvailable indica si hay una GPU NVIDIA utilizable (nvidia-smi la lista).
+// El resultado se calcula una sola vez.
+func NvidiaAvailable() bool {
+	nvidiaOnce.Do(func() {
+		out, err := exec.Command("nvidia-smi", "-L").Output()
+		nvidiaAvailable = err == nil && strings.Contains(string(out), "GPU")
+	})
+	return nvidiaAvailable
+}
+
+// softwareFallback adapta las pasadas a un equipo sin GPU NVIDIA: quita -hwaccel cuda,
+// cambia los codificadores NVENC por su equivalente por software y traduce las
+// opciones propias de NVENC (-cq, presets p1-p7)
+func softwareFallback(passes [][]string) [][]string {
+	res := make([][]string, 0, len(passes))
+	for _, args := range passes {
+		var out []string
+		nvenc := false
+		for i := 0; i < len(args); i++ {
+			if args[i] == "-c:v" && i+1 < len(args) {
+				_, nvenc = softwareEquivalents[args[i+1]]
+			}
+		}
+		for i := 0; i < len(args); i++ {
+			a := args[i]
+			hasValue := i+1 < len(args)
+			switch {
+			case (a == "-hwaccel" || a == "-hwaccel_output_format") && hasValue && (args[i+1] == "cuda" || args[i+1] == "auto"):
+				i++
+			case a == "-c:v" && hasValue && softwareEquivalents[args[i+1]] != "":
+				out = append(out, a, softwareEquivalents[args[i+1]])
+				i++
+			case nvenc && a == "-cq" && hasValue:
+				out = append(out, "-crf", args[i+1])
+				i++
+			case nvenc && a == "-preset" && hasValue && len(args[i+1]) == 2 && args[i+1][0] == 'p':
+				out = append(out, a, "medium")
+				i++
+			default:
+				out = append(out, a)
+			}
+		}
+		res = append(res, out)
+	}
+	return res
+}
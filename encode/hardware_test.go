@@ -0,0 +1,37 @@
+package encode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSoftwareFallback(t *testing.T) {
+	tests := []struct {
+		in, want []string
+	}{
+		{
+			in:   []string{"-y", "-hwaccel", "cuda", "-hwaccel_output_format", "cuda", "-i", "in.mkv", "-c:v", "hevc_nvenc", "-cq", "24", "-preset", "p7", "-c:a", "aac", "out.mkv"},
+			want: []string{"-y", "-i", "in.mkv", "-c:v", "libx265", "-crf", "24", "-preset", "medium", "-c:a", "aac", "out.mkv"},
+		},
+		{
+			// Los presets que no son de NVENC se conservan
+			in:   []string{"-hwaccel", "auto", "-i", "in.mkv", "-c:v", "h264_nvenc", "-preset", "slow", "out.mp4"},
+			want: []string{"-i", "in.mkv", "-c:v", "libx264", "-preset", "slow", "out.mp4"},
+		},
+		{
+			// Sin NVENC no se traducen -cq ni los presets, y otros aceleradores se quedan
+			in:   []string{"-hwaccel", "qsv", "-i", "in.mkv", "-c:v", "libvpx-vp9", "-cq", "30", "-preset", "p1", "out.webm"},
+			want: []string{"-hwaccel", "qsv", "-i", "in.mkv", "-c:v", "libvpx-vp9", "-cq", "30", "-preset", "p1", "out.webm"},
+		},
+		{
+			in:   []string{"-i", "in.mkv", "-c:v", "av1_nvenc", "-pass", "1", "-an", "-f", "null", "-"},
+			want: []string{"-i", "in.mkv", "-c:v", "libsvtav1", "-pass", "1", "-an", "-f", "null", "-"},
+		},
+	}
+	for _, tt := range tests {
+		got := softwareFallback([][]string{tt.in})
+		if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+			t.Errorf("softwareFallback(%q) =\n %q\nse esperaba\n %q", tt.in, got, tt.want)
+		}
+	}
+}
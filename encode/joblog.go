@@ -0,0 +1,145 @@
+package encode
+
+import (
+	"bytes"
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/history"
+	"mediacraft/i18n"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// jobLog es el registro de un trabajo: los comandos ejecutados y la salida completa
+// de 7z y ffmpeg, para poder diagnosticar los fallos. Se guarda en log_dir (por
+// defecto <carpeta de configuración>/logs) o, con log_dir = salida, junto al primer
+// archivo de salida; hasta saber cuál es, lo escrito se guarda en memoria. Un
+// *jobLog nil (log_dir = no) descarta todo.
+type jobLog struct {
+	cfg  *config.Config
+	name string
+	path string
+	f    *os.File
+	buf  bytes.Buffer
+	mu   sync.Mutex
+}
+
+// newJobLog prepara el registro del trabajo de entry sobre input
+func newJobLog(cfg *config.Config, entry history.Entry, input string) *jobLog {
+	if strings.EqualFold(cfg.LogDir, "no") {
+		return nil
+	}
+	name := filepath.Base(input)
+	name = sanitizeFileName(strings.TrimSuffix(name, filepath.Ext(name)))
+	l := &jobLog{cfg: cfg, name: entry.Start.Format("20060102-150405") + "-" + name + ".log"}
+	fmt.Fprintf(l, "# mediacraft %s %s (%s)\n", entry.Type, input, entry.Start.Format("2006-01-02 15:04:05"))
+	if !strings.EqualFold(cfg.LogDir, "salida") {
+		l.open(defaultLogDir(cfg))
+	}
+	return l
+}
+
+// defaultLogDir devuelve log_dir o, si no se indicó, <carpeta de configuración>/logs
+func defaultLogDir(cfg *config.Config) string {
+	if cfg.LogDir != "" && !strings.EqualFold(cfg.LogDir, "salida") {
+		return cfg.LogDir
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return os.TempDir()
+	}
+	return filepath.Join(dir, "logs")
+}
+
+// open crea el archivo en dir y vuelca lo escrito hasta ahora. Si ya existe uno con
+// ese nombre (otro trabajo con una entrada del mismo nombre en el mismo segundo, en
+// un lote, watch o el servidor), añade un sufijo: " (1)", " (2)"...
+func (l *jobLog) open(dir string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		return
+	}
+	var path string
+	err := os.MkdirAll(dir, 0755)
+	base := strings.TrimSuffix(l.name, ".log")
+	for n := 0; err == nil; n++ {
+		path = filepath.Join(dir, l.name)
+		if n > 0 {
+			path = filepath.Join(dir, fmt.Sprintf("%s (%d).log", base, n))
+		}
+		l.f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			break
+		}
+		if os.IsExist(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Warnf("No se pudo crear el registro del trabajo: %v", err)
+		return
+	}
+	l.path = path
+	l.f.Write(l.buf.Bytes())
+	l.buf.Reset()
+}
+
+// place fija la carpeta del registro con log_dir = salida (la del primer archivo de salida)
+func (l *jobLog) place(outDir string) {
+	if l != nil && l.path == "" {
+		l.open(outDir)
+	}
+}
+
+// Path devuelve la ruta del registro, creándolo en la carpeta por defecto si aún no
+// tiene una (p.ej. el trabajo falló antes de saber el archivo de salida)
+func (l *jobLog) Path() string {
+	if l == nil {
+		return ""
+	}
+	if l.path == "" {
+		l.open(defaultLogDir(l.cfg))
+	}
+	return l.path
+}
+
+// Write añade p al registro con los secretos ocultos
+func (l *jobLog) Write(p []byte) (int, error) {
+	if l == nil {
+		return len(p), nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.cfg.Redact(string(p))
+	if l.f != nil {
+		l.f.WriteString(s)
+	} else {
+		l.buf.WriteString(s)
+	}
+	return len(p), nil
+}
+
+// Close cierra el archivo del registro
+func (l *jobLog) Close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+}
+
+// hint devuelve " (registro: <ruta>)" para añadir a los mensajes de error, o "" si
+// no hay registro
+func (l *jobLog) hint() string {
+	if path := l.Path(); path != "" {
+		return fmt.Sprintf(i18n.T(" (registro: %s)"), path)
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package encode
+
+import (
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/probe"
+	"mediacraft/utils"
+	"strings"
+)
+
+// Nombres legibles de los idiomas más habituales, para el título de cada pista
+var languageNames = map[string]string{
+	"spa": "Español",
+	"es":  "Español",
+	"eng": "Inglés",
+	"en":  "Inglés",
+	"cat": "Catalán",
+	"fre": "Francés",
+	"fra": "Francés",
+	"ger": "Alemán",
+	"deu": "Alemán",
+	"ita": "Italiano",
+	"jpn": "Japonés",
+	"por": "Portugués",
+}
+
+// selectAudio devuelve el índice (entre las pistas de audio) de la pista a usar: la
+// única si sólo hay una, la española si hay varias y, si no, la marcada por defecto
+// o la de más canales (la que elegiría ffmpeg); -1 si no hay audio. Con varias
+// pistas se fija siempre con -map (ver streamArgs), para que lo que se comprueba al
+// copiar sea lo que codifica ffmpeg.
+func selectAudio(media probe.Info) int {
+	audios := media.ByType("audio")
+	if len(audios) <= 1 {
+		return len(audios) - 1
+	}
+	for i, s := range audios {
+		if s.Language == "spa" || s.Language == "es" {
+			return i
+		}
+	}
+	best := 0
+	for i, s := range audios {
+		def, bestDef := s.Disposition["default"] != 0, audios[best].Disposition["default"] != 0
+		if def && !bestDef || def == bestDef && s.Channels > audios[best].Channels {
+			best = i
+		}
+	}
+	return best
+}
+
+// metadataArgs construye los argumentos de ffmpeg para escribir etiquetas del contenedor
+// (título, serie, temporada, episodio) e idioma/título de cada pista de audio y subtítulos
+func metadataArgs(cfg *config.Config, media probe.Info, inputName, realPath, profile, outExt string) []string {
+	if !config.IsTrue(cfg.ProfileOption(profile, "metadatos", "true")) {
+		return nil
+	}
+	var args []string
+	outExt = strings.ToLower(outExt)
+	name := utils.ParseMediaName(inputName)
+	if !name.IsEpisode() {
+		if alt := utils.ParseMediaName(realPath); alt.IsEpisode() {
+			name = alt
+		}
+	}
+	// Etiquetas globales
+	if name.IsEpisode() {
+		args = append(args,
+			"-metadata", fmt.Sprintf("title=%s - S%02dE%02d", name.Title, name.Season, name.Episode),
+			"-metadata", "show="+name.Title,
+			"-metadata", fmt.Sprintf("season_number=%d", name.Season),
+			"-metadata", fmt.Sprintf("episode_sort=%d", name.Episode),
+			"-metadata", fmt.Sprintf("episode_id=S%02dE%02d", name.Season, name.Episode),
+		)
+		if outExt == ".mp4" || outExt == ".m4v" {
+			args = append(args, "-metadata", "media_type=10") // Serie de TV (iTunes/Plex)
+		}
+	} else if name.Title != "" {
+		args = append(args, "-metadata", "title="+name.Title)
+	}
+
+	// Idioma y título de cada pista de audio y subtítulos de la salida: el audio es
+	// sólo el seleccionado y los subtítulos los de outputSubtitles, en su orden
+	p := cfg.Profiles[profile]
+	if selected := selectAudio(media); selected >= 0 && !contains(p.Audio, "-an") {
+		args = append(args, streamMetadata("a:0", media.ByType("audio")[selected])...)
+	}
+	subtitles := media.ByType("subtitle")
+	for n, i := range outputSubtitles(cfg, media, profile, outExt) {
+		args = append(args, streamMetadata(fmt.Sprintf("s:%d", n), subtitles[i])...)
+	}
+	return args
+}
+
+// streamMetadata devuelve las etiquetas de idioma y título de la pista de la salida
+// indicada por spec (a:0, s:1...) a partir de la pista de origen s. Sin idioma
+// conocido no escribe nada.
+func streamMetadata(spec string, s probe.Stream) []string {
+	lang := s.Language
+	if lang == "" || lang == "und" {
+		return nil
+	}
+	args := []string{"-metadata:s:" + spec, "language=" + lang}
+	title := s.Tags["title"]
+	if title == "" {
+		title = languageNames[lang]
+	}
+	if title != "" {
+		args = append(args, "-metadata:s:"+spec, "title="+title)
+	}
+	return args
+}
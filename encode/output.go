@@ -0,0 +1,259 @@
+package encode
+
+import (
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/i18n"
+	"mediacraft/probe"
+	"mediacraft/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Plantilla por defecto si la configuración no define output_name
+const defaultOutputName = "{name}.{ext}"
+
+// templateValues reúne los valores disponibles para la plantilla de nombre de salida:
+// los deducidos del nombre del archivo y los obtenidos con ffprobe
+func templateValues(realPath, inputName, profile, outExt string, media probe.Info) map[string]string {
+	base := fileNameWithExt(realPath)
+	if info, err := os.Stat(realPath); err != nil || !info.IsDir() {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	parsed := utils.ParseMediaName(inputName)
+	if !parsed.IsEpisode() {
+		if alt := utils.ParseMediaName(realPath); alt.IsEpisode() {
+			parsed = alt
+		}
+	}
+	vals := map[string]string{
+		"name":    base,
+		"title":   parsed.Title,
+		"season":  "",
+		"episode": "",
+		"profile": profile,
+		"ext":     strings.TrimPrefix(outExt, "."),
+	}
+	if parsed.Season > 0 {
+		vals["season"] = strconv.Itoa(parsed.Season)
+	}
+	if parsed.Episode > 0 {
+		vals["episode"] = strconv.Itoa(parsed.Episode)
+	}
+	if v, ok := media.Video(); ok {
+		vals["vcodec"] = v.CodecName
+		if v.Height > 0 {
+			vals["width"] = strconv.Itoa(v.Width)
+			vals["height"] = strconv.Itoa(v.Height)
+			vals["resolution"] = strconv.Itoa(v.Height) + "p"
+		}
+	}
+	if a := media.ByType("audio"); len(a) > 0 {
+		idx := selectAudio(media)
+		vals["acodec"] = a[idx].CodecName
+		vals["lang"] = a[idx].Language
+	}
+	return vals
+}
+
+// renderTemplate sustituye los marcadores {clave} o {clave:02} (relleno con ceros)
+// de la plantilla. Los bloques entre < y > se omiten enteros si alguno de sus
+// marcadores está vacío, p.ej. "{title}< - S{season:02}E{episode:02}>".
+func renderTemplate(tpl string, vals map[string]string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(tpl); i++ {
+		switch tpl[i] {
+		case '<':
+			end := strings.IndexByte(tpl[i:], '>')
+			if end == -1 {
+				return "", fmt.Errorf(i18n.T("plantilla %q: falta '>'"), tpl)
+			}
+			block, empty, err := renderPlaceholders(tpl[i+1:i+end], vals)
+			if err != nil {
+				return "", err
+			}
+			if !empty {
+				sb.WriteString(block)
+			}
+			i += end
+		default:
+			end := strings.IndexByte(tpl[i:], '<')
+			if end == -1 {
+				end = len(tpl) - i
+			}
+			text, _, err := renderPlaceholders(tpl[i:i+end], vals)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(text)
+			i += end - 1
+		}
+	}
+	return sb.String(), nil
+}
+
+// renderPlaceholders sustituye los marcadores de un trozo de plantilla e indica si
+// alguno de ellos quedó vacío
+func renderPlaceholders(tpl string, vals map[string]string) (string, bool, error) {
+	var sb strings.Builder
+	empty := false
+	for {
+		open := strings.IndexByte(tpl, '{')
+		if open == -1 {
+			sb.WriteString(tpl)
+			break
+		}
+		end := strings.IndexByte(tpl[open:], '}')
+		if end == -1 {
+			return "", false, fmt.Errorf(i18n.T("plantilla %q: falta '}'"), tpl)
+		}
+		sb.WriteString(tpl[:open])
+		key := tpl[open+1 : open+end]
+		width := 0
+		if colon := strings.IndexByte(key, ':'); colon != -1 {
+			w, err := strconv.Atoi(key[colon+1:])
+			if err != nil {
+				return "", false, fmt.Errorf(i18n.T("plantilla: formato no válido en {%s}"), key)
+			}
+			key, width = key[:colon], w
+		}
+		v, ok := vals[key]
+		if !ok {
+			return "", false, fmt.Errorf(i18n.T("plantilla: marcador desconocido {%s}"), key)
+		}
+		if v == "" {
+			empty = true
+		}
+		for len(v) < width && v != "" {
+			v = "0" + v
+		}
+		sb.WriteString(v)
+		tpl = tpl[open+end+1:]
+	}
+	return sb.String(), empty, nil
+}
+
+// sanitizeFileName elimina caracteres no válidos en nombres de archivo de Windows
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '<', '>', ':', '"', '|', '?', '*':
+			return -1
+		}
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(strings.TrimSpace(name), ".")
+	if name == "" {
+		name = "output"
+	}
+	return name
+}
+
+// outputPath calcula la ruta de salida a partir de la plantilla (output_name del perfil,
+// la general o la indicada con --output) y de output_dir. Si override es una carpeta
+// existente o termina en separador, sólo cambia la carpeta de salida.
+func outputPath(cfg *config.Config, realPath, inputName, profile, outExt string, media probe.Info, override string) (string, error) {
+	tpl := cfg.ProfileOption(profile, "output_name", cfg.OutputName)
+	if tpl == "" {
+		tpl = defaultOutputName
+	}
+	dir := cfg.OutputDir
+	if override != "" {
+		switch {
+		case isDirOverride(override):
+			dir = override
+		default:
+			dir = filepath.Dir(override)
+			tpl = filepath.Base(override)
+			if filepath.Ext(tpl) == "" {
+				tpl += ".{ext}"
+			}
+		}
+	}
+	// Los separadores de la plantilla permiten crear subcarpetas. Una plantilla
+	// absoluta (/media/{title}, C:\Series\{title}) no usa output_dir y conserva su
+	// raíz, que no se limpia como los nombres.
+	tpl = strings.ReplaceAll(tpl, "\\", "/")
+	root := templateRoot(tpl)
+	vals := templateValues(realPath, inputName, profile, outExt, media)
+	parts := []string{root}
+	for _, part := range strings.Split(tpl[len(root):], "/") {
+		if part == "" {
+			continue // Barras repetidas
+		}
+		name, err := renderTemplate(part, vals)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, sanitizeFileName(name))
+	}
+	out := filepath.Join(parts...)
+	if dir != "" && root == "" {
+		// Convertir la carpeta de salida a ruta absoluta si es relativa
+		if !filepath.IsAbs(dir) {
+			cwd, _ := os.Getwd()
+			dir = filepath.Join(cwd, dir)
+		}
+		out = filepath.Join(dir, out)
+	}
+	return out, nil
+}
+
+// isDirOverride indica si --output es una carpeta: existe o termina en separador
+func isDirOverride(override string) bool {
+	if info, err := os.Stat(override); err == nil && info.IsDir() {
+		return true
+	}
+	return strings.HasSuffix(override, "/") || strings.HasSuffix(override, "\\")
+}
+
+// overrideExt devuelve la extensión de --output cuando es un archivo con extensión
+// fija (no {ext}), que manda sobre la del perfil; "" en otro caso
+func overrideExt(override string) string {
+	if override == "" || isDirOverride(override) {
+		return ""
+	}
+	ext := strings.ToLower(filepath.Ext(override))
+	if strings.ContainsAny(ext, "{}<>") {
+		return ""
+	}
+	return ext
+}
+
+// templateRoot devuelve la raíz de una plantilla absoluta (con los separadores ya
+// cambiados a /): la unidad o recurso de red en Windows (C:, //servidor/recurso)
+// seguida de la barra inicial, o "" si la plantilla es relativa
+func templateRoot(tpl string) string {
+	root := filepath.VolumeName(tpl)
+	if strings.HasPrefix(tpl[len(root):], "/") {
+		root += "/"
+	}
+	return root
+}
+
+// resolveCollision aplica la política de colisión (skip, overwrite, suffix) cuando el
+// archivo de salida ya existe. Devuelve la ruta final y si hay que saltar el archivo.
+func resolveCollision(out, policy string) (string, bool) {
+	if _, err := os.Stat(out); err != nil {
+		return out, false
+	}
+	switch policy {
+	case "skip":
+		return out, true
+	case "overwrite":
+		return out, false
+	}
+	ext := filepath.Ext(out)
+	base := strings.TrimSuffix(out, ext)
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if _, err := os.Stat(candidate); err != nil {
+			return candidate, false
+		}
+	}
+}
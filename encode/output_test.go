@@ -0,0 +1,104 @@
+package encode
+
+import (
+	"mediacraft/config"
+	"mediacraft/probe"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	vals := map[string]string{
+		"name": "Serie.S01E02.1080p", "title": "Serie", "season": "1", "episode": "2",
+		"profile": "telegram", "ext": "mp4", "lang": "",
+	}
+	tests := []struct {
+		tpl     string
+		want    string
+		wantErr bool
+	}{
+		{tpl: "{name}.{ext}", want: "Serie.S01E02.1080p.mp4"},
+		{tpl: "sin marcadores", want: "sin marcadores"},
+		{tpl: "", want: ""},
+		{tpl: "{season:02}x{episode:03}", want: "01x002"},
+		// El relleno no recorta ni rellena valores vacíos
+		{tpl: "{title:2}", want: "Serie"},
+		{tpl: "[{lang:03}]", want: "[]"},
+		// Bloques opcionales: se omiten si algún marcador está vacío
+		{tpl: "{title}< - S{season:02}E{episode:02}> [{profile}].{ext}", want: "Serie - S01E02 [telegram].mp4"},
+		{tpl: "{title}< ({lang})>.{ext}", want: "Serie.mp4"},
+		{tpl: "<{title}>< ({lang})><-{profile}>", want: "Serie-telegram"},
+		{tpl: "<sin marcadores>", want: "sin marcadores"},
+		{tpl: "a<>b", want: "ab"},
+		// Un > suelto fuera de un bloque se deja tal cual
+		{tpl: "a>b", want: "a>b"},
+		// Errores
+		{tpl: "{title}< - S{season:02}", wantErr: true},
+		{tpl: "{title", wantErr: true},
+		{tpl: "<{title>", wantErr: true},
+		{tpl: "{desconocido}", wantErr: true},
+		{tpl: "<{desconocido}>", wantErr: true},
+		{tpl: "{season:dos}", wantErr: true},
+		{tpl: "{season:}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := renderTemplate(tt.tpl, vals)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("renderTemplate(%q) = %q, se esperaba un error", tt.tpl, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("renderTemplate(%q): error inesperado: %v", tt.tpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("renderTemplate(%q) = %q, se esperaba %q", tt.tpl, got, tt.want)
+		}
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		outputDir, tpl, override string
+		want                     string
+	}{
+		{outputDir: dir, tpl: "{title}/S{season:02}E{episode:02}.{ext}", want: filepath.Join(dir, "Serie", "S01E02.mp4")},
+		{outputDir: dir, tpl: "{title}//{name}.{ext}", want: filepath.Join(dir, "Serie", "Serie.S01E02.mp4")},
+		// Una plantilla absoluta no usa output_dir
+		{outputDir: dir, tpl: "/media/{title}/{name}.{ext}", want: filepath.Join("/media", "Serie", "Serie.S01E02.mp4")},
+		{outputDir: dir, override: filepath.Join(dir, "otro") + "/", want: filepath.Join(dir, "otro", "Serie.S01E02.mp4")},
+		{outputDir: dir, override: filepath.Join(dir, "x"), want: filepath.Join(dir, "x.mp4")},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{OutputDir: tt.outputDir, OutputName: tt.tpl}
+		got, err := outputPath(cfg, "/in/Serie.S01E02.mkv", "Serie.S01E02.mkv", "movil", ".mp4", probe.Info{}, tt.override)
+		if err != nil {
+			t.Errorf("outputPath(%q, %q): error inesperado: %v", tt.tpl, tt.override, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("outputPath(%q, %q) = %q, se esperaba %q", tt.tpl, tt.override, got, tt.want)
+		}
+	}
+}
+
+func TestOverrideExt(t *testing.T) {
+	dir := t.TempDir()
+	for override, want := range map[string]string{
+		"":                "",
+		"x.MP4":           ".mp4",
+		"salida/x.mkv":    ".mkv",
+		"x":               "",
+		"{name}.{ext}":    "",
+		"salidas/":        "",
+		dir:               "",
+		"{title}<.{ext}>": "",
+	} {
+		if got := overrideExt(override); got != want {
+			t.Errorf("overrideExt(%q) = %q, se esperaba %q", override, got, want)
+		}
+	}
+}
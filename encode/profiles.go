@@ -0,0 +1,209 @@
+package encode
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/i18n"
+	"mediacraft/platform"
+	"mediacraft/targets"
+	"os/exec"
+	"strings"
+)
+
+// Resolved es un perfil tal y como se ejecutaría: contenedor y argumentos de ffmpeg
+// de cada pasada (con ENTRADA y SALIDA en lugar de los archivos reales)
+type Resolved struct {
+	Name     string
+	Ext      string
+	Format   string
+	Fallback bool // Se han cambiado codificadores NVENC por software (sin GPU)
+	Target   string
+	Extends  []string // Cadena de herencia del perfil (extends)
+	Includes []string // Fragmentos incluidos
+	Options  map[string]string
+	Passes   [][]string
+}
+
+// ResolveProfile devuelve los argumentos de ffmpeg completos de un perfil, incluido
+// el cambio a software si no hay GPU NVIDIA
+func ResolveProfile(cfg *config.Config, profile string) (Resolved, error) {
+	def, ok := cfg.Profiles[profile]
+	if !ok {
+		return Resolved{}, fmt.Errorf(i18n.T("perfil desconocido: %s"), profile)
+	}
+	outExt, ffFormat := profileFormat(cfg, profile)
+	r := Resolved{Name: profile, Options: def.Options,
+		Extends: def.Extends, Includes: def.Includes}
+	if t, ok := targets.Get(cfg.ProfileOption(profile, "target", "")); ok {
+		r.Target = t.Name
+		if !t.SupportsContainer(outExt) {
+			outExt = t.Containers[0]
+			ffFormat = formatForExt(outExt)
+		}
+	}
+	r.Ext, r.Format = outExt, ffFormat
+	r.Passes = buildPasses(cfg, profile, "ENTRADA", nil, nil, ffFormat, "SALIDA"+outExt)
+	if !NvidiaAvailable() {
+		r.Passes = softwareFallback(r.Passes)
+		r.Fallback = true
+	}
+	return r, nil
+}
+
+// Final devuelve los argumentos de la pasada que escribe el archivo de salida
+func (r Resolved) Final() []string {
+	return r.Passes[len(r.Passes)-1]
+}
+
+// OutputOptions devuelve las opciones de salida de la última pasada (opción → valor)
+func (r Resolved) OutputOptions() map[string]string {
+	return outputOptions(r.Final())
+}
+
+// Capabilities son los codificadores, formatos de salida, filtros y aceleradores
+// del ffmpeg instalado
+type Capabilities struct {
+	Encoders map[string]bool
+	Muxers   map[string]bool
+	Filters  map[string]bool
+	HWAccels map[string]bool
+}
+
+// LoadCapabilities consulta al ffmpeg instalado qué admite
+func LoadCapabilities() (*Capabilities, error) {
+	c := &Capabilities{}
+	var err error
+	if c.Encoders, err = ffmpegList("-encoders", 1); err != nil {
+		return nil, err
+	}
+	if c.Muxers, err = ffmpegList("-muxers", 1); err != nil {
+		return nil, err
+	}
+	if c.Filters, err = ffmpegList("-filters", 1); err != nil {
+		return nil, err
+	}
+	if c.HWAccels, err = ffmpegList("-hwaccels", 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ffmpegList ejecuta ffmpeg -encoders/-muxers/... y devuelve los nombres de la
+// columna indicada de las líneas que siguen a la cabecera
+func ffmpegList(flag string, column int) (map[string]bool, error) {
+	ffmpeg, err := platform.LookTool("ffmpeg")
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command(ffmpeg, "-hide_banner", flag).Output()
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("no se pudo ejecutar ffmpeg %s: %v"), flag, err)
+	}
+	names := map[string]bool{}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	header := true
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if header {
+			// La cabecera termina en una línea " ------" (o en el título en -hwaccels)
+			if strings.HasPrefix(line, "--") || (column == 0 && strings.HasSuffix(line, ":")) {
+				header = false
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) <= column {
+			continue
+		}
+		// Los formatos pueden tener varios nombres separados por comas (matroska,webm)
+		for _, n := range strings.Split(fields[column], ",") {
+			names[n] = true
+		}
+	}
+	return names, sc.Err()
+}
+
+// Validate comprueba que el ffmpeg instalado admite todo lo que usa el perfil y
+// devuelve los problemas encontrados
+func (r Resolved) Validate(c *Capabilities) []string {
+	var problems []string
+	seen := map[string]bool{}
+	add := func(format string, a ...interface{}) {
+		msg := fmt.Sprintf(i18n.T(format), a...)
+		if !seen[msg] {
+			seen[msg] = true
+			problems = append(problems, msg)
+		}
+	}
+	if !c.Muxers[r.Format] {
+		add("formato de salida %s no disponible", r.Format)
+	}
+	for _, args := range r.Passes {
+		for i := 0; i+1 < len(args); i++ {
+			v := args[i+1]
+			switch args[i] {
+			case "-c:v", "-c:a", "-c:s":
+				if v != "copy" && !c.Encoders[v] {
+					add("codificador %s no disponible", v)
+				}
+			case "-vf", "-af", "-filter:v", "-filter:a":
+				for _, f := range filterNames(v) {
+					if !c.Filters[f] {
+						add("filtro %s no disponible", f)
+					}
+				}
+			case "-hwaccel":
+				if v != "auto" && !c.HWAccels[v] {
+					add("aceleración %s no disponible", v)
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// filterNames extrae los nombres de los filtros de una cadena de filtros de ffmpeg,
+// sin confundir las comas entre comillas o escapadas de los argumentos
+func filterNames(chain string) []string {
+	var parts []string
+	var cur strings.Builder
+	quoted := false
+	for i := 0; i < len(chain); i++ {
+		ch := chain[i]
+		switch {
+		case ch == '\\' && i+1 < len(chain):
+			cur.WriteByte(ch)
+			cur.WriteByte(chain[i+1])
+			i++
+		case ch == '\'':
+			quoted = !quoted
+			cur.WriteByte(ch)
+		case (ch == ',' || ch == ';') && !quoted:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(ch)
+		}
+	}
+	parts = append(parts, cur.String())
+	var names []string
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		for strings.HasPrefix(part, "[") { // Etiquetas de entrada [in]
+			end := strings.Index(part, "]")
+			if end < 0 {
+				break
+			}
+			part = strings.TrimSpace(part[end+1:])
+		}
+		if i := strings.IndexAny(part, "=[@"); i >= 0 {
+			part = part[:i]
+		}
+		if part != "" {
+			names = append(names, part)
+		}
+	}
+	return names
+}
@@ -0,0 +1,205 @@
+package encode
+
+import (
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/i18n"
+	"mediacraft/probe"
+	"strconv"
+	"strings"
+)
+
+// Códec que produce cada codificador de ffmpeg, para compararlo con el de la entrada
+var encoderCodecs = map[string]string{
+	"h264_nvenc": "h264", "libx264": "h264", "h264_qsv": "h264", "h264_amf": "h264", "h264_videotoolbox": "h264",
+	"hevc_nvenc": "hevc", "libx265": "hevc", "hevc_qsv": "hevc", "hevc_amf": "hevc", "hevc_videotoolbox": "hevc",
+	"libaom-av1": "av1", "libsvtav1": "av1", "av1_nvenc": "av1", "av1_qsv": "av1", "librav1e": "av1",
+	"libvpx-vp9": "vp9", "vp9_qsv": "vp9", "libvpx": "vp8",
+	"aac": "aac", "libfdk_aac": "aac", "libopus": "opus", "opus": "opus", "libmp3lame": "mp3",
+	"flac": "flac", "ac3": "ac3", "eac3": "eac3", "libvorbis": "vorbis",
+}
+
+// Margen sobre la tasa de bits del perfil que se acepta al copiar una pista
+const bitrateTolerance = 1.15
+
+// Opciones de salida que sólo tienen sentido al codificar cada tipo de pista
+var videoEncodeOpts = map[string]bool{
+	"-b:v": true, "-preset": true, "-crf": true, "-cq": true, "-qp": true, "-rc": true, "-tune": true,
+	"-profile:v": true, "-level": true, "-level:v": true, "-maxrate": true, "-bufsize": true, "-g": true,
+	"-pix_fmt": true, "-x264-params": true, "-x265-params": true, "-svtav1-params": true, "-pass": true,
+	"-cpu-used": true, "-row-mt": true, "-deadline": true, "-hwaccel": true, "-hwaccel_output_format": true,
+}
+var audioEncodeOpts = map[string]bool{"-b:a": true, "-ac": true, "-ar": true, "-q:a": true}
+
+// remuxPlan indica qué pistas se pueden copiar sin recodificar y por qué
+type remuxPlan struct {
+	copyVideo bool
+	copyAudio bool
+	reasons   []string
+}
+
+// planRemux compara las pistas de la entrada con el objetivo de la última pasada
+// (códec, tasa de bits, filtros, formato de píxel) y, si alguna ya lo cumple, la
+// copia con -c copy. Si el vídeo se copia, sobran las pasadas previas de 2 pasadas.
+// Se desactiva con remux = no en el perfil.
+func planRemux(cfg *config.Config, media probe.Info, passes [][]string, profile string) [][]string {
+	if len(passes) == 0 || !config.IsTrue(cfg.ProfileOption(profile, "remux", "true")) {
+		return passes
+	}
+	final := passes[len(passes)-1]
+	plan := analyzeRemux(media, final)
+	if !plan.copyVideo && !plan.copyAudio {
+		return passes
+	}
+	yellow := "\033[33m"
+	reset := "\033[0m"
+	for _, r := range plan.reasons {
+		log.Infof("%s  Remux inteligente: %s%s\n", yellow, r, reset)
+	}
+	final = rewriteForCopy(final, plan)
+	if plan.copyVideo {
+		return [][]string{final}
+	}
+	res := append([][]string{}, passes[:len(passes)-1]...)
+	return append(res, final)
+}
+
+// analyzeRemux decide qué pistas cumplen ya el objetivo de los argumentos de salida
+func analyzeRemux(media probe.Info, args []string) remuxPlan {
+	var plan remuxPlan
+	opts := outputOptions(args)
+
+	// Vídeo: primera pista que no sea una portada
+	var video *probe.Stream
+	if v, ok := media.Video(); ok {
+		video = &v
+	}
+	if video != nil {
+		target := encoderCodecs[opts["-c:v"]]
+		switch {
+		case target == "" || opts["-c:v"] == "copy":
+		case video.CodecName != target:
+		case opts["-vf"] != "" || opts["-filter:v"] != "" || opts["-s"] != "" || opts["-r"] != "":
+		case opts["-pix_fmt"] != "" && opts["-pix_fmt"] != video.PixFmt:
+		default:
+			limit := parseBitrate(opts["-b:v"])
+			have := video.BitRate
+			if have == 0 {
+				have = estimateVideoBitrate(media)
+			}
+			if limit > 0 && (have == 0 || have > limit*bitrateTolerance) {
+				break
+			}
+			plan.copyVideo = true
+			plan.reasons = append(plan.reasons, fmt.Sprintf(i18n.T("vídeo copiado (%s ya cumple el perfil)"), video.CodecName))
+		}
+	}
+
+	// Audio: la pista seleccionada, que es la que streamArgs pasa a la salida
+	audios := media.ByType("audio")
+	if len(audios) > 0 {
+		audio := audios[selectAudio(media)]
+		target := encoderCodecs[opts["-c:a"]]
+		switch {
+		case target == "" || opts["-c:a"] == "copy":
+		case audio.CodecName != target:
+		case opts["-af"] != "" || opts["-filter:a"] != "" || opts["-ar"] != "":
+		case opts["-ac"] != "" && strconv.Itoa(audio.Channels) != opts["-ac"]:
+		default:
+			limit := parseBitrate(opts["-b:a"])
+			if have := audio.BitRate; limit > 0 && have > limit*bitrateTolerance {
+				break
+			}
+			plan.copyAudio = true
+			plan.reasons = append(plan.reasons, fmt.Sprintf(i18n.T("audio copiado (%s ya cumple el perfil)"), audio.CodecName))
+		}
+	}
+	if plan.copyVideo != plan.copyAudio {
+		if !plan.copyVideo && video != nil {
+			plan.reasons = append(plan.reasons, i18n.T("se recodifica sólo el vídeo"))
+		} else if !plan.copyAudio && len(audios) > 0 {
+			plan.reasons = append(plan.reasons, i18n.T("se recodifica sólo el audio"))
+		}
+	}
+	return plan
+}
+
+// outputOptions extrae las opciones de salida (las que van tras el último -i) como
+// mapa opción → valor; las opciones sin valor conocidas no se incluyen
+func outputOptions(args []string) map[string]string {
+	opts := map[string]string{}
+	start := 0
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-i" {
+			start = i + 2
+		}
+	}
+	for i := start; i+1 < len(args); i++ {
+		if strings.HasPrefix(args[i], "-") && args[i] != "-y" && args[i] != "-an" && args[i] != "-vn" && args[i] != "-sn" {
+			opts[args[i]] = args[i+1]
+			i++
+		}
+	}
+	return opts
+}
+
+// rewriteForCopy sustituye los codificadores por copy en las pistas que se copian y
+// elimina las opciones de codificación que ya no aplican
+func rewriteForCopy(args []string, plan remuxPlan) []string {
+	var res []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		hasValue := i+1 < len(args)
+		switch {
+		case plan.copyVideo && a == "-c:v" && hasValue:
+			res = append(res, a, "copy")
+			i++
+		case plan.copyAudio && a == "-c:a" && hasValue:
+			res = append(res, a, "copy")
+			i++
+		case plan.copyVideo && videoEncodeOpts[a] && hasValue:
+			i++
+		case plan.copyAudio && audioEncodeOpts[a] && hasValue:
+			i++
+		default:
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// parseBitrate convierte valores como 2500k, 5M o 128000 a bits/s (0 si no hay límite)
+func parseBitrate(v string) float64 {
+	v = strings.TrimSpace(strings.ToLower(v))
+	if v == "" {
+		return 0
+	}
+	mult := 1.0
+	switch v[len(v)-1] {
+	case 'k':
+		mult, v = 1000, v[:len(v)-1]
+	case 'm':
+		mult, v = 1000000, v[:len(v)-1]
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0
+	}
+	return n * mult
+}
+
+// estimateVideoBitrate estima la tasa de vídeo como la total del contenedor menos
+// la de las pistas de audio (0 si no se conoce)
+func estimateVideoBitrate(media probe.Info) float64 {
+	total := media.Format.BitRate
+	if total == 0 {
+		return 0
+	}
+	for _, a := range media.ByType("audio") {
+		total -= a.BitRate
+	}
+	if total < 0 {
+		return 0
+	}
+	return total
+}
@@ -0,0 +1,69 @@
+package encode
+
+import (
+	"mediacraft/config"
+	"mediacraft/probe"
+	"reflect"
+	"testing"
+)
+
+// testMedia devuelve un análisis con una pista de vídeo y las de audio indicadas
+func testMedia(video probe.Stream, audios ...probe.Stream) probe.Info {
+	video.CodecType = "video"
+	info := probe.Info{Streams: []probe.Stream{video}}
+	for _, a := range audios {
+		a.CodecType = "audio"
+		info.Streams = append(info.Streams, a)
+	}
+	return info
+}
+
+func TestAnalyzeRemux(t *testing.T) {
+	h264 := probe.Stream{CodecName: "h264", PixFmt: "yuv420p", BitRate: 2000000}
+	aac := probe.Stream{CodecName: "aac", Channels: 2, BitRate: 128000}
+	args := []string{"-y", "-i", "in.mkv", "-c:v", "libx264", "-b:v", "2500k", "-c:a", "aac", "-b:a", "128k"}
+	tests := []struct {
+		name                 string
+		media                probe.Info
+		args                 []string
+		copyVideo, copyAudio bool
+	}{
+		{"todo cumple", testMedia(h264, aac), args, true, true},
+		{"otro códec de vídeo", testMedia(probe.Stream{CodecName: "hevc", BitRate: 2000000}, aac), args, false, true},
+		{"vídeo por encima de la tasa", testMedia(probe.Stream{CodecName: "h264", BitRate: 4000000}, aac), args, false, true},
+		{"tasa de vídeo estimada del contenedor", probe.Info{Format: probe.Format{BitRate: 2100000}, Streams: testMedia(probe.Stream{CodecName: "h264"}, aac).Streams}, args, true, true},
+		{"tasa de vídeo desconocida", testMedia(probe.Stream{CodecName: "h264"}, aac), args, false, true},
+		{"filtro de vídeo", testMedia(h264, aac), append(append([]string{}, args...), "-vf", "scale=640:-2"), false, true},
+		{"otro formato de píxel", testMedia(h264, aac), append(append([]string{}, args...), "-pix_fmt", "yuv420p10le"), false, true},
+		{"otro códec de audio", testMedia(h264, probe.Stream{CodecName: "ac3", Channels: 6}), args, true, false},
+		{"otros canales", testMedia(h264, aac), append(append([]string{}, args...), "-ac", "6"), true, false},
+		// Con varias pistas se compara la que se selecciona (la española)
+		{"audio seleccionado", testMedia(h264, probe.Stream{CodecName: "dts", Channels: 6}, probe.Stream{CodecName: "aac", Channels: 2, Language: "spa"}), args, true, true},
+		{"sin audio", testMedia(h264), args, true, false},
+		{"ya se copia", testMedia(h264, aac), []string{"-i", "in.mkv", "-c:v", "copy", "-c:a", "copy"}, false, false},
+	}
+	for _, tt := range tests {
+		plan := analyzeRemux(tt.media, tt.args)
+		if plan.copyVideo != tt.copyVideo || plan.copyAudio != tt.copyAudio {
+			t.Errorf("%s: copia vídeo %v, audio %v; se esperaba %v, %v", tt.name, plan.copyVideo, plan.copyAudio, tt.copyVideo, tt.copyAudio)
+		}
+	}
+}
+
+func TestPlanRemux(t *testing.T) {
+	media := testMedia(probe.Stream{CodecName: "hevc", BitRate: 3000000}, probe.Stream{CodecName: "opus", Channels: 2})
+	passes := [][]string{
+		{"-y", "-i", "in.mkv", "-c:v", "libx265", "-b:v", "5000k", "-pass", "1", "-an", "-f", "null", "-"},
+		{"-i", "in.mkv", "-c:v", "libx265", "-b:v", "5000k", "-pass", "2", "-c:a", "aac", "-b:a", "320k", "out.mkv"},
+	}
+	// El vídeo se copia: sobra la primera pasada y las opciones de codificación de vídeo
+	want := [][]string{{"-i", "in.mkv", "-c:v", "copy", "-c:a", "aac", "-b:a", "320k", "out.mkv"}}
+	if got := planRemux(&config.Config{}, media, passes, "plex"); !reflect.DeepEqual(got, want) {
+		t.Errorf("planRemux = %q, se esperaba %q", got, want)
+	}
+	// remux = no lo desactiva
+	cfg := &config.Config{Profiles: map[string]config.Profile{"plex": {Options: map[string]string{"remux": "no"}}}}
+	if got := planRemux(cfg, media, passes, "plex"); !reflect.DeepEqual(got, passes) {
+		t.Errorf("planRemux con remux = no = %q, se esperaba %q", got, passes)
+	}
+}
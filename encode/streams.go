@@ -0,0 +1,208 @@
+package encode
+
+import (
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/probe"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Nombres de archivo que se usan como portada si están junto al vídeo
+var coverNames = []string{"cover.jpg", "cover.png", "folder.jpg", "folder.png", "poster.jpg", "poster.png"}
+
+// streamArgs decide qué pistas de la entrada pasan a la salida y construye los
+// argumentos de selección (-map), capítulos, metadatos de origen, adjuntos (fuentes
+// de subtítulos ASS) y portada. Devuelve argumentos de entrada adicionales y de salida.
+// Si el contenedor de salida no admite algo que tiene la entrada, avisa y lo omite.
+func streamArgs(cfg *config.Config, media probe.Info, realPath, profile, outExt string) (inArgs, outArgs []string) {
+	yellow := "\033[33m"
+	reset := "\033[0m"
+	outExt = strings.ToLower(outExt)
+	audioOnly := isAudioExt(outExt)
+	isMKV := outExt == ".mkv" || outExt == ".mka"
+	isMP4 := outExt == ".mp4" || outExt == ".m4v" || outExt == ".mov"
+
+	// Capítulos y metadatos globales del origen
+	if config.IsTrue(cfg.ProfileOption(profile, "capitulos", "true")) {
+		outArgs = append(outArgs, "-map_chapters", "0")
+		if len(media.Chapters) > 0 && isMP4 {
+			log.Infof("%s  %d capítulos convertidos a pista de capítulos MP4%s\n", yellow, len(media.Chapters), reset)
+		}
+	} else {
+		outArgs = append(outArgs, "-map_chapters", "-1")
+	}
+	if config.IsTrue(cfg.ProfileOption(profile, "metadatos_origen", "true")) {
+		outArgs = append(outArgs, "-map_metadata", "0")
+	} else {
+		outArgs = append(outArgs, "-map_metadata", "-1")
+	}
+
+	// Pistas: sólo se fija la selección (-map) cuando hace falta, porque en cuanto
+	// hay un -map ffmpeg deja de elegir pistas automáticamente
+	attachments := media.ByType("attachment")
+	subtitles := media.ByType("subtitle")
+	keepAttachments := config.IsTrue(cfg.ProfileOption(profile, "adjuntos", "true")) && len(attachments) > 0
+	if keepAttachments && !isMKV {
+		log.Warnf("El contenedor %s no admite adjuntos: se omiten %d (fuentes de subtítulos)", outExt, len(attachments))
+		keepAttachments = false
+	}
+	cover := ""
+	if !audioOnly && (isMKV || isMP4) {
+		cover = findCover(cfg, realPath, profile)
+	}
+	// Con varias pistas de audio se fija siempre la de selectAudio: es la que se
+	// comprueba para copiarla (remux y target). Los subtítulos también se fijan, para
+	// pasarlos todos y saber su posición en la salida (ver metadataArgs).
+	selected := selectAudio(media)
+	keptSubs := outputSubtitles(cfg, media, profile, outExt)
+	explicit := keepAttachments || cover != "" || len(media.ByType("audio")) > 1 || len(keptSubs) > 0
+	if explicit {
+		if !audioOnly {
+			outArgs = append(outArgs, "-map", "0:v:0?")
+		}
+		if selected >= 0 {
+			outArgs = append(outArgs, "-map", fmt.Sprintf("0:a:%d", selected))
+		} else {
+			outArgs = append(outArgs, "-map", "0:a:0?")
+		}
+		switch {
+		case len(keptSubs) == 0:
+		case isMKV:
+			outArgs = append(outArgs, "-map", "0:s?", "-c:s", "copy")
+		default:
+			// MP4 sólo admite subtítulos de texto, convertidos a mov_text
+			for _, i := range keptSubs {
+				outArgs = append(outArgs, "-map", fmt.Sprintf("0:s:%d", i))
+			}
+			outArgs = append(outArgs, "-c:s", "mov_text")
+		}
+		if keepAttachments {
+			outArgs = append(outArgs, "-map", "0:t?", "-c:t", "copy")
+		}
+	}
+	switch dropped := len(subtitles) - len(keptSubs); {
+	case dropped == 0 || audioOnly:
+	case isMP4:
+		log.Warnf("El contenedor %s sólo admite subtítulos de texto: se omiten %d de imagen", outExt, dropped)
+	default:
+		log.Warnf("Los subtítulos de la entrada no se copian al contenedor %s", outExt)
+	}
+
+	// Portada
+	switch {
+	case cover == "":
+	case isMKV:
+		mime := "image/jpeg"
+		if strings.HasSuffix(strings.ToLower(cover), ".png") {
+			mime = "image/png"
+		}
+		// Los adjuntos con -attach van detrás de los copiados de la entrada
+		idx := 0
+		if keepAttachments {
+			idx = len(attachments)
+		}
+		outArgs = append(outArgs, "-attach", cover,
+			fmt.Sprintf("-metadata:s:t:%d", idx), "mimetype="+mime,
+			fmt.Sprintf("-metadata:s:t:%d", idx), "filename=cover"+strings.ToLower(filepath.Ext(cover)))
+	case isMP4:
+		// La portada va detrás del vídeo de la entrada, si lo hay (0:v:0? puede no
+		// seleccionar nada)
+		idx := 0
+		if len(media.ByType("video")) > 0 {
+			idx = 1
+		}
+		inArgs = append(inArgs, "-i", cover)
+		outArgs = append(outArgs, "-map", "1:v:0",
+			fmt.Sprintf("-c:v:%d", idx), "copy", fmt.Sprintf("-disposition:v:%d", idx), "attached_pic")
+	}
+	return inArgs, outArgs
+}
+
+// Códecs de subtítulos de texto, los que se pueden convertir a mov_text para MP4
+var textSubtitleCodecs = map[string]bool{
+	"subrip": true, "srt": true, "ass": true, "ssa": true, "webvtt": true, "mov_text": true, "text": true,
+}
+
+// outputSubtitles devuelve el índice (entre los subtítulos de la entrada) de los que
+// pasan a la salida, en el orden en que quedan: todos en MKV, los de texto en MP4 y
+// ninguno en el resto de contenedores o si el perfil los desactiva (subtitulos = none)
+func outputSubtitles(cfg *config.Config, media probe.Info, profile, outExt string) []int {
+	if contains(cfg.Profiles[profile].Subs, "-sn") {
+		return nil
+	}
+	var kept []int
+	for i, s := range media.ByType("subtitle") {
+		switch strings.ToLower(outExt) {
+		case ".mkv", ".mka":
+			kept = append(kept, i)
+		case ".mp4", ".m4v", ".mov":
+			if textSubtitleCodecs[s.CodecName] {
+				kept = append(kept, i)
+			}
+		}
+	}
+	return kept
+}
+
+// findCover busca la portada configurada en el perfil (portada = ruta|auto|none)
+// o, en modo auto, una imagen conocida junto al archivo de entrada
+func findCover(cfg *config.Config, realPath, profile string) string {
+	opt := cfg.ProfileOption(profile, "portada", "auto")
+	switch strings.ToLower(opt) {
+	case "none", "no", "false":
+		return ""
+	case "auto":
+		dir := filepath.Dir(realPath)
+		if info, err := os.Stat(realPath); err == nil && info.IsDir() {
+			dir = realPath
+		}
+		for _, n := range coverNames {
+			p := filepath.Join(dir, n)
+			if _, err := os.Stat(p); err == nil {
+				return p
+			}
+		}
+		return ""
+	default:
+		if _, err := os.Stat(opt); err != nil {
+			log.Warnf("No se encontró la portada %s", opt)
+			return ""
+		}
+		return opt
+	}
+}
+
+// isAudioExt indica si la extensión de salida es de un contenedor sólo de audio
+func isAudioExt(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".mp3", ".m4a", ".aac", ".flac", ".opus", ".ogg", ".wav":
+		return true
+	}
+	return false
+}
+
+// finishArgs añade las entradas extra tras la entrada principal y cierra la línea
+// de ffmpeg con las opciones de salida extra, el formato (-f) y el archivo de salida.
+// La colisión con un archivo existente ya está resuelta, así que se añade -y para que
+// ffmpeg no pregunte.
+func finishArgs(args, extraIn, extraOut []string, ffFormat, out string) []string {
+	if len(args) == 0 || args[0] != "-y" {
+		args = append([]string{"-y"}, args...)
+	}
+	if len(extraIn) > 0 {
+		for i := 0; i+1 < len(args); i++ {
+			if args[i] == "-i" {
+				rest := append([]string{}, args[i+2:]...)
+				args = append(append(args[:i+2], extraIn...), rest...)
+				break
+			}
+		}
+	}
+	args = append(args, extraOut...)
+	if ffFormat != "" && out != "" {
+		args = append(args, "-f", ffFormat)
+	}
+	return append(args, out)
+}
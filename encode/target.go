@@ -0,0 +1,197 @@
+package encode
+
+import (
+	"fmt"
+	"mediacraft/i18n"
+	"mediacraft/probe"
+	"mediacraft/targets"
+	"strings"
+)
+
+// Codificador por defecto para cada códec cuando el del perfil no sirve para el destino
+var softwareEncoders = map[string]string{
+	"h264": "libx264", "hevc": "libx265", "vp9": "libvpx-vp9", "vp8": "libvpx", "av1": "libsvtav1",
+	"aac": "aac", "mp3": "libmp3lame", "opus": "libopus", "vorbis": "libvorbis", "flac": "flac",
+	"ac3": "ac3", "eac3": "eac3", "alac": "alac",
+}
+
+// toTargetStream convierte la información de ffprobe al formato del catálogo de destinos
+func toTargetStream(s probe.Stream) targets.Stream {
+	return targets.Stream{Codec: s.CodecName, Profile: s.Profile, Level: s.Level, Width: s.Width,
+		Height: s.Height, PixFmt: s.PixFmt, Channels: s.Channels}
+}
+
+// applyTarget decide pista a pista qué hay que transcodificar para que la salida se
+// reproduzca directamente en el destino (target = ... en el perfil): copia las pistas
+// compatibles, cambia el codificador si el del perfil no sirve y añade los arreglos
+// necesarios (formato de píxel, escalado, canales, etiqueta hvc1, faststart).
+func applyTarget(t targets.Target, media probe.Info, passes [][]string, outExt string) [][]string {
+	if len(passes) == 0 {
+		return passes
+	}
+	yellow := "\033[33m"
+	reset := "\033[0m"
+	final := passes[len(passes)-1]
+	opts := outputOptions(final)
+	var plan remuxPlan
+	var videoOpts, audioOpts []string
+	var filters []string
+	videoEncoder, audioEncoder := opts["-c:v"], opts["-c:a"]
+	outVideoCodec := encoderCodecs[videoEncoder]
+
+	// Vídeo
+	var video *probe.Stream
+	if v, ok := media.Video(); ok {
+		video = &v
+	}
+	if video != nil && !isAudioExt(outExt) && !contains(final, "-vn") {
+		problems := t.CheckVideo(toTargetStream(*video))
+		if opts["-vf"] != "" || opts["-filter:v"] != "" {
+			problems = append(problems, i18n.T("el perfil aplica filtros de vídeo"))
+		}
+		if len(problems) == 0 {
+			plan.copyVideo = true
+			outVideoCodec = video.CodecName
+			plan.reasons = append(plan.reasons, fmt.Sprintf(i18n.T("vídeo copiado (%s compatible)"), video.CodecName))
+		} else {
+			plan.reasons = append(plan.reasons, i18n.T("vídeo recodificado: ")+strings.Join(problems, ", "))
+			if !t.SupportsVideoCodec(outVideoCodec) {
+				codec := t.Video[0].Codec
+				enc := softwareEncoders[codec]
+				if strings.Contains(videoEncoder, "nvenc") && (codec == "h264" || codec == "hevc" || codec == "av1") {
+					enc = codec + "_nvenc"
+				}
+				plan.reasons = append(plan.reasons, fmt.Sprintf(i18n.T("el codificador %s no sirve para %s, se usa %s"), videoEncoder, t.Name, enc))
+				videoEncoder, outVideoCodec = enc, codec
+				videoOpts = append(videoOpts, "-c:v", enc)
+			}
+			fixOpts, fixFilters := t.VideoFixes(toTargetStream(*video))
+			videoOpts = append(videoOpts, fixOpts...)
+			filters = append(filters, fixFilters...)
+			if outVideoCodec == "h264" && strings.Contains(video.PixFmt, "10") && !contains(fixOpts, "-pix_fmt") {
+				videoOpts = append(videoOpts, "-pix_fmt", "yuv420p") // H.264 de 10 bits casi nunca se reproduce directamente
+			}
+			if outVideoCodec == "h264" && opts["-profile:v"] == "" {
+				videoOpts = append(videoOpts, "-profile:v", "high")
+				for _, vc := range t.Video {
+					if vc.Codec == "h264" && vc.MaxLevel > 0 && opts["-level"] == "" && opts["-level:v"] == "" {
+						videoOpts = append(videoOpts, "-level:v", fmt.Sprintf("%d.%d", vc.MaxLevel/10, vc.MaxLevel%10))
+					}
+				}
+			}
+		}
+	}
+
+	// Audio: la pista seleccionada, que es la que streamArgs pasa a la salida con -map
+	if audios := media.ByType("audio"); len(audios) > 0 && !contains(final, "-an") {
+		audio := audios[selectAudio(media)]
+		problems := t.CheckAudio(toTargetStream(audio))
+		if opts["-af"] != "" || opts["-filter:a"] != "" {
+			problems = append(problems, i18n.T("el perfil aplica filtros de audio"))
+		}
+		if len(problems) == 0 {
+			plan.copyAudio = true
+			plan.reasons = append(plan.reasons, fmt.Sprintf(i18n.T("audio copiado (%s compatible)"), audio.CodecName))
+		} else {
+			plan.reasons = append(plan.reasons, i18n.T("audio recodificado: ")+strings.Join(problems, ", "))
+			codec := encoderCodecs[audioEncoder]
+			if codec == "" || !contains(t.Audio, codec) {
+				enc := softwareEncoders[t.Audio[0]]
+				plan.reasons = append(plan.reasons, fmt.Sprintf(i18n.T("el codificador %s no sirve para %s, se usa %s"), audioEncoder, t.Name, enc))
+				audioOpts = append(audioOpts, "-c:a", enc)
+			}
+			audioOpts = append(audioOpts, t.AudioFixes(toTargetStream(audio))...)
+		}
+	}
+
+	// Arreglos del contenedor
+	var containerOpts []string
+	if ext := strings.ToLower(outExt); ext == ".mp4" || ext == ".m4v" || ext == ".mov" {
+		if t.HEVCTag && outVideoCodec == "hevc" {
+			containerOpts = append(containerOpts, "-tag:v", "hvc1")
+		}
+		if t.FastStart {
+			containerOpts = append(containerOpts, "-movflags", "+faststart")
+		}
+	}
+
+	for _, r := range plan.reasons {
+		log.Infof("%s  Destino %s: %s%s\n", yellow, t.Name, r, reset)
+	}
+	// Las pasadas previas sólo existen para el vídeo: sobran si se copia
+	if plan.copyVideo {
+		passes = passes[len(passes)-1:]
+	}
+	res := make([][]string, 0, len(passes))
+	for i, args := range passes {
+		isFinal := i == len(passes)-1
+		if isFinal {
+			args = rewriteForCopy(args, plan)
+		}
+		if !plan.copyVideo {
+			args = setOutputOptions(args, videoOpts)
+			for _, f := range filters {
+				args = addVideoFilter(args, f)
+			}
+		}
+		if isFinal {
+			if !plan.copyAudio {
+				args = setOutputOptions(args, audioOpts)
+			}
+			args = setOutputOptions(args, containerOpts)
+		}
+		res = append(res, args)
+	}
+	return res
+}
+
+// setOutputOptions fija pares opción/valor en las opciones de salida: sustituye el
+// valor si la opción ya está tras el último -i o la añade antes de -f/salida
+func setOutputOptions(args, pairs []string) []string {
+	args = append([]string{}, args...)
+	start := 0
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-i" {
+			start = i + 2
+		}
+	}
+	for p := 0; p+1 < len(pairs); p += 2 {
+		key, val := pairs[p], pairs[p+1]
+		found := false
+		for i := start; i+1 < len(args); i++ {
+			if args[i] == key {
+				args[i+1] = val
+				found = true
+				break
+			}
+		}
+		if !found {
+			at := len(args) - 1
+			if len(args) >= 3 && args[len(args)-3] == "-f" {
+				at = len(args) - 3
+			}
+			args = append(args[:at], append([]string{key, val}, args[at:]...)...)
+		}
+	}
+	return args
+}
+
+// addVideoFilter añade un filtro a la cadena -vf existente o crea una nueva
+func addVideoFilter(args []string, filter string) []string {
+	opts := outputOptions(args)
+	for _, key := range []string{"-vf", "-filter:v"} {
+		if v, ok := opts[key]; ok {
+			return setOutputOptions(args, []string{key, v + "," + filter})
+		}
+	}
+	return setOutputOptions(args, []string{"-vf", filter})
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package encode
+
+import (
+	"mediacraft/probe"
+	"mediacraft/targets"
+	"testing"
+)
+
+func TestApplyTarget(t *testing.T) {
+	hevc := probe.Stream{CodecName: "hevc", Profile: "Main", Level: 120, Width: 1920, Height: 1080, PixFmt: "yuv420p"}
+	h264 := probe.Stream{CodecName: "h264", Profile: "High", Level: 40, Width: 1920, Height: 1080, PixFmt: "yuv420p"}
+	aac := probe.Stream{CodecName: "aac", Channels: 2}
+	twoPass := [][]string{
+		{"-y", "-i", "in.mkv", "-c:v", "h264_nvenc", "-b:v", "2500k", "-pass", "1", "-an", "-f", "null", "-"},
+		{"-i", "in.mkv", "-c:v", "h264_nvenc", "-b:v", "2500k", "-pass", "2", "-c:a", "aac", "-b:a", "128k", "-f", "mp4", "out.mp4"},
+	}
+	tests := []struct {
+		name   string
+		target string
+		media  probe.Info
+		passes int               // Pasadas que quedan
+		want   map[string]string // Opciones de la última pasada
+		absent []string          // Opciones que no deben quedar
+	}{
+		{
+			name: "todo compatible", target: "apple", media: testMedia(hevc, aac), passes: 1,
+			want:   map[string]string{"-c:v": "copy", "-c:a": "copy", "-tag:v": "hvc1", "-movflags": "+faststart"},
+			absent: []string{"-b:v", "-pass", "-b:a"},
+		},
+		{
+			name: "códec de vídeo no admitido", target: "telegram", media: testMedia(hevc, aac), passes: 2,
+			want:   map[string]string{"-c:v": "h264_nvenc", "-profile:v": "high", "-level:v": "4.1", "-c:a": "copy", "-movflags": "+faststart"},
+			absent: []string{"-tag:v"},
+		},
+		{
+			name: "H.264 de 10 bits", target: "telegram", media: testMedia(probe.Stream{CodecName: "h264", Profile: "High 10", Level: 40, Width: 1920, Height: 1080, PixFmt: "yuv420p10le"}, aac), passes: 2,
+			want: map[string]string{"-c:v": "h264_nvenc", "-pix_fmt": "yuv420p"},
+		},
+		{
+			// El audio que se comprueba es el seleccionado (el español), no el primero
+			name: "audio seleccionado no admitido", target: "telegram",
+			media:  testMedia(h264, probe.Stream{CodecName: "aac", Channels: 2}, probe.Stream{CodecName: "dts", Channels: 6, Language: "spa"}),
+			passes: 1,
+			want:   map[string]string{"-c:v": "copy", "-c:a": "aac", "-ac": "2"},
+		},
+	}
+	for _, tt := range tests {
+		target, ok := targets.Get(tt.target)
+		if !ok {
+			t.Fatalf("no existe el destino %s", tt.target)
+		}
+		got := applyTarget(target, tt.media, twoPass, ".mp4")
+		if len(got) != tt.passes {
+			t.Errorf("%s: %d pasadas, se esperaban %d: %q", tt.name, len(got), tt.passes, got)
+			continue
+		}
+		final := got[len(got)-1]
+		opts := outputOptions(final)
+		for k, v := range tt.want {
+			if opts[k] != v {
+				t.Errorf("%s: %s = %q, se esperaba %q: %q", tt.name, k, opts[k], v, final)
+			}
+		}
+		for _, k := range tt.absent {
+			if _, ok := opts[k]; ok {
+				t.Errorf("%s: sobra %s: %q", tt.name, k, final)
+			}
+		}
+		if final[len(final)-1] != "out.mp4" {
+			t.Errorf("%s: la salida no es el último argumento: %q", tt.name, final)
+		}
+	}
+}
@@ -2,4 +2,8 @@ module mediacraft
 
 go 1.20
 
-require gopkg.in/ini.v1 v1.67.0 // indirect
+require (
+	github.com/BurntSushi/toml v1.3.2
+	gopkg.in/ini.v1 v1.67.0
+	gopkg.in/yaml.v3 v3.0.1
+)
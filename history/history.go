@@ -0,0 +1,253 @@
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mediacraft/config"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var log = logging.For("history")
+
+// Estados de un trabajo en el historial
+const (
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+	StatusSkipped  = "skipped"
+)
+
+// Nombre del archivo de historial dentro de la carpeta de configuración
+const fileName = "history.jsonl"
+
+// Entry es un trabajo registrado en el historial
+type Entry struct {
+	ID             string     `json:"id"`
+	Type           string     `json:"type"` // convert u order
+	Inputs         []string   `json:"inputs"`
+	Output         string     `json:"output,omitempty"`
+	Profile        string     `json:"profile,omitempty"`
+	Args           [][]string `json:"args,omitempty"` // Argumentos de ffmpeg de cada pasada
+	Start          time.Time  `json:"start"`
+	End            time.Time  `json:"end"`
+	Status         string     `json:"status"`
+	Error          string     `json:"error,omitempty"`
+	InputSize      int64      `json:"input_size,omitempty"`
+	OutputSize     int64      `json:"output_size,omitempty"`
+	InputDuration  float64    `json:"input_duration,omitempty"`
+	OutputDuration float64    `json:"output_duration,omitempty"`
+	Log            string     `json:"log,omitempty"` // Registro del trabajo con la salida de ffmpeg y 7z
+}
+
+// New crea una entrada para un trabajo que empieza ahora
+func New(jobType string, inputs ...string) Entry {
+	now := time.Now()
+	return Entry{ID: strconv.FormatInt(now.UnixNano(), 36), Type: jobType, Inputs: inputs, Start: now}
+}
+
+// Finish completa la entrada con la hora de fin, el estado según err y el tamaño
+// de la salida. Si el estado ya se fijó (p.ej. skipped) no se cambia.
+func (e *Entry) Finish(err error) {
+	e.End = time.Now()
+	switch {
+	case e.Status != "":
+	case errors.Is(err, context.Canceled):
+		e.Status = StatusCanceled
+	case err != nil:
+		e.Status = StatusFailed
+		e.Error = err.Error()
+	default:
+		e.Status = StatusOK
+	}
+	if e.Output != "" {
+		if info, err := os.Stat(e.Output); err == nil && !info.IsDir() {
+			e.OutputSize = info.Size()
+		}
+	}
+}
+
+var mu sync.Mutex
+
+// Path devuelve la ruta del archivo de historial
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Record añade una entrada al historial (si historial = true en la configuración).
+// Los errores sólo se avisan: no registrar el historial no debe hacer fallar un trabajo.
+func Record(cfg *config.Config, e Entry) {
+	if !cfg.EnableHistory {
+		return
+	}
+	if err := appendEntry(e); err != nil {
+		log.Warnf("No se pudo guardar el historial: %v", err)
+	}
+}
+
+// appendEntry escribe la entrada como una línea JSON al final del historial
+func appendEntry(e Entry) error {
+	p, err := Path()
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load lee todas las entradas del historial, de la más antigua a la más reciente.
+// Las líneas dañadas se ignoran.
+func Load() ([]Entry, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+// Filter selecciona entradas del historial; los campos vacíos no filtran
+type Filter struct {
+	Type    string
+	Profile string
+	Status  string
+	Since   time.Time
+	Until   time.Time
+}
+
+// Match indica si la entrada cumple el filtro
+func (f Filter) Match(e Entry) bool {
+	if f.Type != "" && e.Type != f.Type {
+		return false
+	}
+	if f.Profile != "" && e.Profile != f.Profile {
+		return false
+	}
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && e.Start.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Start.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Apply devuelve las entradas que cumplen el filtro
+func (f Filter) Apply(entries []Entry) []Entry {
+	var res []Entry
+	for _, e := range entries {
+		if f.Match(e) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// ParseDate interpreta fechas AAAA-MM-DD o AAAA-MM-DD HH:MM en hora local
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(i18n.T("fecha no válida %q (use AAAA-MM-DD)"), s)
+}
+
+// WriteJSON exporta las entradas como un array JSON
+func WriteJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// WriteCSV exporta las entradas como CSV (los argumentos de cada pasada separados por " | ")
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "type", "status", "profile", "start", "end", "seconds", "inputs", "output",
+		"input_size", "output_size", "input_duration", "output_duration", "error", "args", "log"})
+	for _, e := range entries {
+		var passes []string
+		for _, a := range e.Args {
+			passes = append(passes, strings.Join(a, " "))
+		}
+		cw.Write([]string{
+			e.ID, e.Type, e.Status, e.Profile,
+			e.Start.Format(time.RFC3339), e.End.Format(time.RFC3339),
+			strconv.FormatFloat(e.End.Sub(e.Start).Seconds(), 'f', 0, 64),
+			strings.Join(e.Inputs, ";"), e.Output,
+			strconv.FormatInt(e.InputSize, 10), strconv.FormatInt(e.OutputSize, 10),
+			strconv.FormatFloat(e.InputDuration, 'f', 2, 64), strconv.FormatFloat(e.OutputDuration, 'f', 2, 64),
+			e.Error, strings.Join(passes, " | "), e.Log,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteTable muestra las entradas como tabla legible
+func WriteTable(w io.Writer, entries []Entry) {
+	fmt.Fprintf(w, "%-19s  %-7s  %-8s  %-10s  %8s  %10s  %s\n", i18n.T("FECHA"), i18n.T("TIPO"), i18n.T("ESTADO"), i18n.T("PERFIL"), i18n.T("TIEMPO"), i18n.T("SALIDA"), i18n.T("ENTRADA"))
+	for _, e := range entries {
+		input := ""
+		if len(e.Inputs) > 0 {
+			input = filepath.Base(e.Inputs[0])
+		}
+		fmt.Fprintf(w, "%-19s  %-7s  %-8s  %-10s  %8s  %10s  %s\n",
+			e.Start.Format("2006-01-02 15:04:05"), e.Type, e.Status, e.Profile,
+			e.End.Sub(e.Start).Round(time.Second), utils.FormatSize(e.OutputSize), input)
+	}
+}
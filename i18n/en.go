@@ -0,0 +1,363 @@
+package i18n
+
+// english traduce al inglés los mensajes, por su texto en español. Los colores, los
+// iconos y los %s tienen que quedar igual que en el original.
+var english = map[string]string{
+	// Ayuda del CLI
+	"   MediaCraft CLI - Ayuda\n":                               "   MediaCraft CLI - Help\n",
+	" Uso: mediacraft <subcomando> [opciones] [argumentos]\n\n": " Usage: mediacraft <subcommand> [options] [arguments]\n\n",
+	" Subcomandos:\n":         " Subcommands:\n",
+	"\n Opciones globales:\n": "\n Global options:\n",
+	"   --config <archivo>          Archivo de configuración (también MEDIACRAFT_CONFIG)\n":                       "   --config <file>             Configuration file (also MEDIACRAFT_CONFIG)\n",
+	"   --set <seccion.clave=valor> Sobrescribir una opción de la configuración\n":                                "   --set <section.key=value>   Override a configuration option\n",
+	"   --verbose                   Mostrar también los mensajes de depuración (comandos, rutas)\n":               "   --verbose                   Also show debug messages (commands, paths)\n",
+	"   --quiet                     Mostrar sólo los errores\n":                                                   "   --quiet                     Only show errors\n",
+	"   --log-file <archivo>        Añadir todos los mensajes, con fecha y nivel, al archivo\n":                   "   --log-file <file>           Append every message, with date and level, to the file\n",
+	"   --output-format <formato>   text o json (un evento JSON por línea: cola, progreso, fin, error, movido)\n": "   --output-format <format>    text or json (one JSON event per line: queued, progress, finished, error, moved)\n",
+	"   -v, --version               Mostrar versión\n":                                                            "   -v, --version               Show version\n",
+	"   -h, --help [subcomando]     Mostrar ayuda (también mediacraft <subcomando> -h)\n":                         "   -h, --help [subcommand]     Show help (also mediacraft <subcommand> -h)\n",
+	"\n Formas abreviadas (compatibles con versiones anteriores):\n":                                              "\n Short forms (compatible with earlier versions):\n",
+	"   -c, --convert <ruta>        Igual que convert <ruta> (admite -o/--output)\n":                              "   -c, --convert <path>        Same as convert <path> (accepts -o/--output)\n",
+	"   -o, --order <carpeta>       Igual que order <carpeta>\n":                                                  "   -o, --order <folder>        Same as order <folder>\n",
+	"\n Idioma de los mensajes: idioma = es|en en la configuración o LANG\n":                                      "\n Message language: idioma = es|en in the configuration, or LANG\n",
+	"    Autor: %s\n": "    Author: %s\n",
+	"    Fecha: %s\n": "    Date: %s\n",
+	"25 de julio de 2025 (primera versión estable)":         "July 25, 2025 (first stable release)",
+	"%sNo se especificó ninguna acción.%s\n":                "%sNo action specified.%s\n",
+	"Use -h o --help para ver las opciones.\n":              "Use -h or --help to see the options.\n",
+	"\033[31m[ERROR] opción desconocida: %s\033[0m\n":       "\033[31m[ERROR] unknown option: %s\033[0m\n",
+	"\033[31m[ERROR] subcomando desconocido: %s\033[0m\n":   "\033[31m[ERROR] unknown subcommand: %s\033[0m\n",
+	"--output-format: formato desconocido %q (text o json)": "--output-format: unknown format %q (text or json)",
+	"--verbose y --quiet no se pueden usar juntos":          "--verbose and --quiet cannot be used together",
+	"Configuración: %s":                                     "Configuration: %s",
+
+	// Subcomandos
+	"Convertir vídeos (--profile, --output, --workers, --dry-run)":            "Convert videos (--profile, --output, --workers, --dry-run)",
+	"Ordenar episodios en carpetas de temporada (--dry-run)":                  "Sort episodes into season folders (--dry-run)",
+	"Extraer archivos comprimidos o partidos (--output, --dry-run)":           "Extract compressed or split archives (--output, --dry-run)",
+	"Contenedor, pistas y perfil que se aplicaría (--format json)":            "Container, streams and the profile that would apply (--format json)",
+	"Perfiles: list, show <perfil>, validate [perfil...], diff <a> <b>":       "Profiles: list, show <profile>, validate [profile...], diff <a> <b>",
+	"Historial de trabajos (--profile, --status, --since, --format json|csv)": "Job history (--profile, --status, --since, --format json|csv)",
+	"Comprobar herramientas, GPU, configuración y perfiles":                   "Check tools, GPU, configuration and profiles",
+	"Vigilar una carpeta y procesar lo que llegue":                            "Watch a folder and process whatever arrives",
+	"Servidor HTTP con cola de trabajos y panel web":                          "HTTP server with a job queue and web dashboard",
+	"Pasar la configuración a YAML, TOML o JSON":                              "Convert the configuration to YAML, TOML or JSON",
+	"<archivo|carpeta>...": "<file|folder>...",
+	"<carpeta>...":         "<folder>...",
+	"<archivo>...":         "<file>...",
+	"<carpeta>":            "<folder>",
+	"[acción]":             "[action]",
+	"[dirección]":          "[address]",
+	"convert <archivo>":    "convert <file>",
+
+	// Opciones de cada subcomando
+	"Uso: %s\n":                 "Usage: %s\n",
+	" [opciones] ":              " [options] ",
+	"\nOpciones:\n":             "\nOptions:\n",
+	" (por defecto %s)":         " (default %s)",
+	"opción desconocida: %s":    "unknown option: %s",
+	"falta el valor de %s":      "missing value for %s",
+	"%s (use mediacraft %s -h)": "%s (see mediacraft %s -h)",
+	"perfil":                    "profile",
+	"ruta":                      "path",
+	"carpeta":                   "folder",
+	"tipo":                      "type",
+	"estado":                    "status",
+	"fecha":                     "date",
+	"formato":                   "format",
+	"Convierte vídeos, archivos comprimidos o carpetas enteras con un perfil":                                     "Converts videos, compressed archives or whole folders with a profile",
+	"Perfil o lista de perfiles (telegram,plex,audio); si no, archivo@perfil, .mediacraft.conf o default_profile": "Profile or list of profiles (telegram,plex,audio); otherwise file@profile, .mediacraft.conf or default_profile",
+	"Plantilla, archivo o carpeta de salida":                                                                      "Output template, file or folder",
+	"Conversiones simultáneas":                                                                                    "Simultaneous conversions",
+	"Mostrar los comandos de ffmpeg sin ejecutarlos":                                                              "Show the ffmpeg commands without running them",
+	"Ordena los episodios de cada carpeta en subcarpetas \"Temporada N\"":                                         "Sorts the episodes of each folder into \"Temporada N\" subfolders",
+	"Mostrar qué se movería, sin mover nada":                                                                      "Show what would be moved, without moving anything",
+	"Extrae archivos comprimidos (también partidos en volúmenes) con 7z":                                          "Extracts compressed archives (also split into volumes) with 7z",
+	"Carpeta de destino (por defecto, una con el nombre del archivo junto a él)":                                  "Destination folder (by default, one named after the archive next to it)",
+	"Mostrar qué se extraería y dónde, sin extraer nada":                                                          "Show what would be extracted and where, without extracting anything",
+	"Comprueba ffmpeg, ffprobe, 7z, la GPU, la configuración y los perfiles":                                      "Checks ffmpeg, ffprobe, 7z, the GPU, the configuration and the profiles",
+	"Lista, filtra y exporta el historial de trabajos":                                                            "Lists, filters and exports the job history",
+	"Filtrar por tipo (convert, order)":                                                                           "Filter by type (convert, order)",
+	"Filtrar por perfil":                                                                                          "Filter by profile",
+	"Filtrar por estado (ok, failed, canceled, skipped)":                                                          "Filter by status (ok, failed, canceled, skipped)",
+	"Sólo trabajos desde la fecha (AAAA-MM-DD)":                                                                   "Only jobs since the date (YYYY-MM-DD)",
+	"Sólo trabajos anteriores a la fecha (AAAA-MM-DD)":                                                            "Only jobs before the date (YYYY-MM-DD)",
+	"Formato de salida: table, json o csv":                                                                        "Output format: table, json or csv",
+	"Mostrar sólo los N trabajos más recientes":                                                                   "Show only the N most recent jobs",
+	"Muestra contenedor, duración, tamaño, pistas y el perfil que se aplicaría a cada archivo":                    "Shows container, duration, size, streams and the profile that would apply to each file",
+	"Formato de salida: table o json":                                                                             "Output format: table or json",
+	"[list | show <perfil> | validate [perfil...] | diff <a> <b>]":                                                "[list | show <profile> | validate [profile...] | diff <a> <b>]",
+	"Lista los perfiles, muestra sus comandos de ffmpeg, los valida contra el ffmpeg instalado o los compara":     "Lists the profiles, shows their ffmpeg commands, validates them against the installed ffmpeg or compares them",
+	"Vigila la carpeta y convierte u ordena lo que llegue (sección [watch])":                                      "Watches the folder and converts or sorts whatever arrives ([watch] section)",
+	"Servidor HTTP con cola de trabajos y panel web (sección [servidor])":                                         "HTTP server with a job queue and web dashboard ([servidor] section)",
+	"convert <archivo> [salida] [--to yaml|toml|json|ini]":                                                        "convert <file> [output] [--to yaml|toml|json|ini]",
+	"Convierte un archivo de configuración a otro formato (sin salida, lo escribe por pantalla)":                  "Converts a configuration file to another format (without output, prints it)",
+
+	// Errores y resultados de los subcomandos
+	"falta el archivo o la carpeta a convertir (use mediacraft convert -h)":       "missing file or folder to convert (see mediacraft convert -h)",
+	"falta la carpeta a ordenar (use mediacraft order -h)":                        "missing folder to sort (see mediacraft order -h)",
+	"falta el archivo a extraer (use mediacraft extract -h)":                      "missing archive to extract (see mediacraft extract -h)",
+	"falta el archivo o la carpeta a analizar (use mediacraft probe -h)":          "missing file or folder to analyse (see mediacraft probe -h)",
+	"uso: mediacraft watch <carpeta>":                                             "usage: mediacraft watch <folder>",
+	"uso: mediacraft profiles show <perfil>":                                      "usage: mediacraft profiles show <profile>",
+	"uso: mediacraft profiles diff <perfil> <perfil>":                             "usage: mediacraft profiles diff <profile> <profile>",
+	"uso: mediacraft config convert <archivo> [salida] [--to yaml|toml|json|ini]": "usage: mediacraft config convert <file> [output] [--to yaml|toml|json|ini]",
+	"--workers: se esperaba un número positivo":                                   "--workers: expected a positive number",
+	"perfil desconocido: %s (disponibles: %s)":                                    "unknown profile: %s (available: %s)",
+	"fallaron %d de %d conversiones":                                              "%d of %d conversions failed",
+	"%s: no hay vídeos ni archivos comprimidos":                                   "%s: no videos or compressed archives",
+	"no hay nada que convertir":                                                   "nothing to convert",
+	"%s: no hay vídeos":                                                           "%s: no videos",
+	"no hay nada que analizar":                                                    "nothing to analyse",
+	"%s: no es un archivo comprimido":                                             "%s: not a compressed archive",
+	"  (ensayo) %s (%d volúmenes) → %s\n":                                         "  (dry run) %s (%d volumes) → %s\n",
+	"%s  Extrayendo %s → %s%s\n":                                                  "%s  Extracting %s → %s%s\n",
+	"%s  Extraído: %s%s\n":                                                        "%s  Extracted: %s%s\n",
+	"formato desconocido: %s":                                                     "unknown format: %s",
+	"acción desconocida: %s (list, show, validate o diff)":                        "unknown action: %s (list, show, validate or diff)",
+	"%s ya existe; no se sobrescribe":                                             "%s already exists; not overwriting it",
+	"\033[32mConfiguración convertida: %s → %s (%s)\033[0m\n":                     "\033[32mConfiguration converted: %s → %s (%s)\033[0m\n",
+	"\033[34m  MediaCraft escuchando en http://%s\033[0m\n":                       "\033[34m  MediaCraft listening on http://%s\033[0m\n",
+
+	// doctor
+	"%s  AVISO  %s%s  %s\n":                       "%s  WARN   %s%s  %s\n",
+	"; instálelo (https://ffmpeg.org)":            "; install it (https://ffmpeg.org)",
+	"; no se podrán extraer archivos comprimidos": "; compressed archives cannot be extracted",
+	"Temporal":                         "Temp",
+	"no se puede escribir en %s: %v":   "cannot write to %s: %v",
+	"NVIDIA detectada: se usará NVENC": "NVIDIA detected: NVENC will be used",
+	"sin GPU NVIDIA: los perfiles NVENC usarán codificadores por software": "no NVIDIA GPU: NVENC profiles will use software encoders",
+	"configuración":                  "configuration",
+	"(integrada)":                    "(built-in)",
+	"(entorno)":                      "(environment)",
+	"default_profile = %s no existe": "default_profile = %s does not exist",
+	"historial":                      "history",
+	"perfil ":                        "profile ",
+	"perfiles":                       "profiles",
+	"%d de %d válidos con el ffmpeg instalado": "%d of %d valid with the installed ffmpeg",
+	"%d problemas encontrados":                 "%d problems found",
+	"versión desconocida":                      "unknown version",
+
+	// probe
+	"fijado por ":  "set by ",
+	"regla %s: %s": "rule %s: %s",
+	"ninguna regla coincide; defecto de [reglas]": "no rule matches; [reglas] default",
+	"ninguna regla coincide; default_profile":     "no rule matches; default_profile",
+	"1 capítulo":           "1 chapter",
+	"%d capítulos":         "%d chapters",
+	"Contenedor":           "Container",
+	"Perfil":               "Profile",
+	"TIPO":                 "TYPE",
+	"CÓDEC":                "CODEC",
+	"IDIOMA":               "LANGUAGE",
+	"DETALLE":              "DETAIL",
+	"vídeo":                "video",
+	"subtítulo":            "subtitle",
+	"adjunto":              "attachment",
+	"datos":                "data",
+	"portada":              "cover",
+	"%d canales":           "%d channels",
+	"(por defecto)":        "(default)",
+	"(forzado)":            "(forced)",
+	"duración desconocida": "unknown duration",
+
+	// profiles
+	"PERFIL":                     "PROFILE",
+	"NOTAS":                      "NOTES",
+	"%d pasadas":                 "%d passes",
+	"hereda de ":                 "extends ",
+	"incluye ":                   "includes ",
+	"\nConfiguración: %s\n":      "\nConfiguration: %s\n",
+	"%sPerfil %s%s\n":            "%sProfile %s%s\n",
+	"  Contenedor: %s (-f %s)\n": "  Container:  %s (-f %s)\n",
+	"  Destino:    %s\n":         "  Target:     %s\n",
+	"  Hereda de:  %s\n":         "  Extends:    %s\n",
+	"  Incluye:    %s\n":         "  Includes:   %s\n",
+	"  %sSin GPU NVIDIA: se usan codificadores por software%s\n": "  %sNo NVIDIA GPU: software encoders are used%s\n",
+	"  Opciones de MediaCraft:\n":                                "  MediaCraft options:\n",
+	"  Pasada %d:\n    ffmpeg %s\n":                              "  Pass %d:\n    ffmpeg %s\n",
+	"%d de %d perfiles no son válidos con el ffmpeg instalado":   "%d of %d profiles are not valid with the installed ffmpeg",
+	"Los perfiles son equivalentes\n":                            "The profiles are equivalent\n",
+	"formato de salida %s no disponible":                         "output format %s not available",
+	"codificador %s no disponible":                               "encoder %s not available",
+	"filtro %s no disponible":                                    "filter %s not available",
+	"aceleración %s no disponible":                               "acceleration %s not available",
+	"no se pudo ejecutar ffmpeg %s: %v":                          "could not run ffmpeg %s: %v",
+
+	// history
+	"FECHA":                               "DATE",
+	"ESTADO":                              "STATUS",
+	"TIEMPO":                              "TIME",
+	"SALIDA":                              "OUTPUT",
+	"ENTRADA":                             "INPUT",
+	"No se pudo guardar el historial: %v": "Could not save the history: %v",
+	"fecha no válida %q (use AAAA-MM-DD)": "invalid date %q (use YYYY-MM-DD)",
+
+	// Conversión
+	"No se pudieron cargar los perfiles: %v":                                                   "Could not load the profiles: %v",
+	"\033[36m  Perfil %s fijado por %s\033[0m\n":                                               "\033[36m  Profile %s set by %s\033[0m\n",
+	"%s: perfil desconocido %s":                                                                "%s: unknown profile %s",
+	"perfil desconocido: %s":                                                                   "unknown profile: %s",
+	"\033[36m  Regla %s (%s) → perfil %s\033[0m\n":                                             "\033[36m  Rule %s (%s) → profile %s\033[0m\n",
+	"\033[36m  Ninguna regla coincide → perfil %s\033[0m\n":                                    "\033[36m  No rule matches → profile %s\033[0m\n",
+	"\033[33m  (ensayo) %s es un archivo comprimido: se extraería antes de convertir\033[0m\n": "\033[33m  (dry run) %s is a compressed archive: it would be extracted before converting\033[0m\n",
+	"No se pudo leer %s: %v%s":                                                                 "Could not read %s: %v%s",
+	"\033[33m\uf1c6  Archivo comprimido detectado y extraído a temporal: %s\033[0m\n":          "\033[33m\uf1c6  Compressed archive detected and extracted to a temporary folder: %s\033[0m\n",
+	"No se pudo analizar %s con ffprobe: %v":                                                   "Could not analyse %s with ffprobe: %v",
+	"No se pudo determinar el archivo de salida (%s): %v":                                      "Could not determine the output file (%s): %v",
+	"\n%s\uf1c0 [SELECCIONADO] Perfil %s%s\n":                                                  "\n%s\uf1c0 [SELECTED] Profile %s%s\n",
+	"\n%s\uf1c0 [SELECCIONADO] Perfiles %s%s\n":                                                "\n%s\uf1c0 [SELECTED] Profiles %s%s\n",
+	"%s\uf2db GPU NVIDIA detectada - usando aceleración por hardware%s\n":                      "%s\uf2db NVIDIA GPU detected - using hardware acceleration%s\n",
+	"%s Sin GPU NVIDIA - se usarán codificadores por software%s\n":                             "%s No NVIDIA GPU - software encoders will be used%s\n",
+	"%s\uf110 Iniciando conversión: %s%s\n":                                                    "%s\uf110 Starting conversion: %s%s\n",
+	"%s\uf05a Archivo de salida: %s%s\n":                                                       "%s\uf05a Output file: %s%s\n",
+	"%s  Pasada %d:%s ffmpeg %s\n":                                                             "%s  Pass %d:%s ffmpeg %s\n",
+	"pasada previa de %s":                                                                      "first pass of %s",
+	"salidas %s":                                                                               "outputs %s",
+	"%s  Ejecución %d (%s):%s ffmpeg %s\n":                                                     "%s  Run %d (%s):%s ffmpeg %s\n",
+	"No se pudo crear la carpeta de salida: %v":                                                "Could not create the output folder: %v",
+	"Falló la ejecución conjunta de %s; se repite cada salida por separado":                    "The combined run of %s failed; repeating each output separately",
+	"\033[33m  Conversión cancelada: %s\033[0m\n":                                              "\033[33m  Conversion canceled: %s\033[0m\n",
+	"La conversión de %s falló: %s%s":                                                          "Conversion of %s failed: %s%s",
+	"Resumen: %s → %s | Perfil: %s | Duración salida: %s | Progreso final: %s":                 "Summary: %s → %s | Profile: %s | Output duration: %s | Final progress: %s",
+	"fallaron %d de %d salidas: %s%s":                                                          "%d of %d outputs failed: %s%s",
+	"\033[33m  Destino %s: el contenedor %s no es compatible, se usa %s\033[0m\n":              "\033[33m  Target %s: container %s is not supported, using %s\033[0m\n",
+	"\033[33m  --output: se usa el contenedor %s en lugar del del perfil (%s)\033[0m\n":        "\033[33m  --output: using container %s instead of the profile's (%s)\033[0m\n",
+	"Destino %s: el contenedor %s no es compatible":                                            "Target %s: container %s is not supported",
+	"\033[33m  %s ya es la salida de otro perfil; %s usa %s\033[0m\n":                          "\033[33m  %s is already another profile's output; %s uses %s\033[0m\n",
+	"\033[33m  El archivo de salida ya existe, se omite: %s\033[0m\n":                          "\033[33m  The output file already exists, skipping: %s\033[0m\n",
+	"\033[34mArchivo de salida final: %s\033[0m\n":                                             "\033[34mFinal output file: %s\033[0m\n",
+	"no se pudo comprobar la salida: %v":                                                       "could not check the output: %v",
+	"la salida no tiene pistas":                                                                "the output has no streams",
+	"la salida dura %s y la entrada %s":                                                        "the output lasts %s and the input %s",
+	"No se pudo crear el registro del trabajo: %v":                                             "Could not create the job log: %v",
+	" (registro: %s)":                                                           " (log: %s)",
+	"plantilla %q: falta '>'":                                                   "template %q: missing '>'",
+	"plantilla %q: falta '}'":                                                   "template %q: missing '}'",
+	"plantilla: formato no válido en {%s}":                                      "template: invalid format in {%s}",
+	"plantilla: marcador desconocido {%s}":                                      "template: unknown placeholder {%s}",
+	"%s  Remux inteligente: %s%s\n":                                             "%s  Smart remux: %s%s\n",
+	"vídeo copiado (%s ya cumple el perfil)":                                    "video copied (%s already meets the profile)",
+	"audio copiado (%s ya cumple el perfil)":                                    "audio copied (%s already meets the profile)",
+	"se recodifica sólo el vídeo":                                               "only the video is re-encoded",
+	"se recodifica sólo el audio":                                               "only the audio is re-encoded",
+	"%s  %d capítulos convertidos a pista de capítulos MP4%s\n":                 "%s  %d chapters converted to an MP4 chapter track%s\n",
+	"El contenedor %s no admite adjuntos: se omiten %d (fuentes de subtítulos)": "Container %s does not support attachments: skipping %d (subtitle fonts)",
+	"Los subtítulos de la entrada no se copian al contenedor %s":                "The input subtitles are not copied to container %s",
+	"El contenedor %s sólo admite subtítulos de texto: se omiten %d de imagen":  "Container %s only supports text subtitles: skipping %d image-based ones",
+	"No se encontró la portada %s":                                              "Cover %s not found",
+	"el perfil aplica filtros de vídeo":                                         "the profile applies video filters",
+	"el perfil aplica filtros de audio":                                         "the profile applies audio filters",
+	"vídeo copiado (%s compatible)":                                             "video copied (%s compatible)",
+	"audio copiado (%s compatible)":                                             "audio copied (%s compatible)",
+	"vídeo recodificado: ":                                                      "video re-encoded: ",
+	"audio recodificado: ":                                                      "audio re-encoded: ",
+	"el codificador %s no sirve para %s, se usa %s":                             "encoder %s does not suit %s, using %s",
+	"%s  Destino %s: %s%s\n":                                                    "%s  Target %s: %s%s\n",
+	"códec de vídeo %s no admitido":                                             "video codec %s not supported",
+	"perfil %s no admitido":                                                     "profile %s not supported",
+	"nivel %d superior a %d":                                                    "level %d above %d",
+	"resolución %dx%d superior a %dx%d":                                         "resolution %dx%d above %dx%d",
+	"formato de píxel %s no admitido":                                           "pixel format %s not supported",
+	"códec de audio %s no admitido":                                             "audio codec %s not supported",
+	"%d canales, máximo %d":                                                     "%d channels, maximum %d",
+	"%s: faltan volúmenes del archivo":                                          "%s: archive volumes are missing",
+	"%s: análisis en caché":                                                     "%s: cached analysis",
+	"%s es una carpeta":                                                         "%s is a folder",
+	"ffprobe: respuesta no válida: %w":                                          "ffprobe: invalid response: %w",
+	"ffprobe: no se reconoce el formato":                                        "ffprobe: unrecognised format",
+
+	// Notificaciones
+	"No se pudo enviar la notificación de Telegram: %s": "Could not send the Telegram notification: %s",
+	"Telegram rechazó la notificación: %s":              "Telegram rejected the notification: %s",
+
+	// Progreso
+	"pasada %d/%d":                      "pass %d/%d",
+	"quedan %s":                         "%s left",
+	"  %s %5.1f%% Lote: %d/%d archivos": "  %s %5.1f%% Batch: %d/%d files",
+	" · %d fallidos":                    " · %d failed",
+	"[AVISO]":                           "[WARNING]",
+
+	// Ordenación de series
+	"%s\uf0ae  Leyendo archivos de la carpeta:%s %s\n":                      "%s\uf0ae  Reading the files in the folder:%s %s\n",
+	"%s\uf1c6  Archivos comprimidos detectados y extraídos a temporal:%s\n": "%s\uf1c6  Compressed archives detected and extracted to a temporary folder:%s\n",
+	"%s\uf15c  %d archivos encontrados. Detectando temporadas...%s\n":       "%s\uf15c  %d files found. Detecting seasons...%s\n",
+	"\n%s\uf07b  Creando carpetas y moviendo archivos...%s\n":               "\n%s\uf07b  Creating folders and moving files...%s\n",
+	"  (ensayo) %s → %s\n":                                                  "  (dry run) %s → %s\n",
+	"No se pudo mover %s: %v":                                               "Could not move %s: %v",
+	"no se pudieron mover %d archivos":                                      "%d files could not be moved",
+	"\n%s\uf058  Ordenación de series completada.%s\n":                      "\n%s\uf058  Series sorting completed.%s\n",
+
+	// Vigilancia de carpetas
+	"Las conversiones se guardan en la carpeta vigilada (output_dir): si se reinicia la vigilancia se volverán a procesar": "Conversions are saved in the watched folder (output_dir): they will be processed again if watching restarts",
+	"no es una carpeta: %s":                      "not a folder: %s",
+	"%s  Vigilando %s (acción: %s, cada %s)%s\n": "%s  Watching %s (action: %s, every %s)%s\n",
+	"\033[33m  Esperando volúmenes de %s%s\n":    "\033[33m  Waiting for volumes of %s%s\n",
+	"%s  Procesado: %s%s\n":                      "%s  Processed: %s%s\n",
+	"no se pudo descomprimir %s: %v":             "could not extract %s: %v",
+	"no se encontraron vídeos":                   "no videos found",
+
+	// Configuración
+	"[mediacraft] colision: valor no válido %q (skip, overwrite o suffix)": "[mediacraft] colision: invalid value %q (skip, overwrite or suffix)",
+	"[mediacraft] idioma: valor no válido %q (es o en)":                    "[mediacraft] idioma: invalid value %q (es or en)",
+	"idioma desconocido %q (es o en)":                                      "unknown language %q (es or en)",
+	"[watch] accion: valor no válido %q (convert, order o convert_order)":  "[watch] accion: invalid value %q (convert, order or convert_order)",
+	"[watch] intervalo: se esperaba un número de segundos positivo":        "[watch] intervalo: expected a positive number of seconds",
+	"[watch] estable: se esperaba un número de segundos":                   "[watch] estable: expected a number of seconds",
+	"[servidor] workers: se esperaba un número positivo":                   "[servidor] workers: expected a positive number",
+	"[herramientas] %s: herramienta desconocida (%s)":                      "[herramientas] %s: unknown tool (%s)",
+	"[herramientas] %s: no se encuentra %s":                                "[herramientas] %s: %s not found",
+	"no se encuentra %s en el PATH (indique su ruta en [herramientas] %s)": "%s not found in the PATH (set its path in [herramientas] %s)",
+	"se esperaba un objeto en el primer nivel":                             "expected an object at the top level",
+	"línea %d: %s: las listas sólo pueden tener valores simples":           "line %d: %s: lists can only hold simple values",
+	"%s: no se admiten listas de tablas":                                   "%s: arrays of tables are not supported",
+	"%s: las listas sólo pueden tener valores simples":                     "%s: lists can only hold simple values",
+	"contenido inesperado tras el objeto principal":                        "unexpected content after the main object",
+	"formato desconocido: %s (ini, yaml, toml o json)":                     "unknown format: %s (ini, yaml, toml or json)",
+	"[%s] herencia circular: %s":                                           "[%s] circular inheritance: %s",
+	"[%s] no existe la sección":                                            "[%s] the section does not exist",
+	"[%s] %s: no existe la sección [%s]":                                   "[%s] %s: section [%s] does not exist",
+	"[%s] extends: sólo se admite en perfiles; use include":                "[%s] extends: only allowed in profiles; use include",
+	"no se encontró el archivo de configuración: %s":                       "configuration file not found: %s",
+	"--set %s: se esperaba seccion.clave=valor":                            "--set %s: expected section.key=value",
+	"%s (de %s)":                                                               "%s (from %s)",
+	"extensión no válida %q":                                                   "invalid extension %q",
+	"acelerador desconocido %q":                                                "unknown accelerator %q",
+	"no tiene sentido con video = none":                                        "makes no sense with video = none",
+	"no tiene sentido con audio = none":                                        "makes no sense with audio = none",
+	"se esperaba un número entre 0 y 63, no %q":                                "expected a number between 0 and 63, not %q",
+	"destino desconocido %q (disponibles: %s)":                                 "unknown target %q (available: %s)",
+	"clave desconocida (use opciones_salida para pasar opciones de ffmpeg)":    "unknown key (use opciones_salida to pass ffmpeg options)",
+	"el perfil descarta vídeo y audio":                                         "the profile drops both video and audio",
+	"tasa de bits no válida %q (p.ej. 2500k, 5M)":                              "invalid bitrate %q (e.g. 2500k, 5M)",
+	"se esperaba un número positivo, no %q":                                    "expected a positive number, not %q",
+	"se esperaba un número entre %d y %d, no %q":                               "expected a number between %d and %d, not %q",
+	"comillas sin cerrar":                                                      "unclosed quotes",
+	"[reglas] %s: falta el perfil":                                             "[reglas] %s: missing profile",
+	"[reglas] %s: perfil desconocido %q":                                       "[reglas] %s: unknown profile %q",
+	"[reglas] %s: se esperaba condición -> perfil":                             "[reglas] %s: expected condition -> profile",
+	"campo desconocido %q (alto, ancho, duracion, tamaño, ruta, nombre o ext)": "unknown field %q (alto, ancho, duracion, tamaño, ruta, nombre or ext)",
+	"%s: falta el operador (>, >=, <, <=, =, != o contiene)":                   "%s: missing operator (>, >=, <, <=, =, != or contiene)",
+	"%s: falta el valor":                                                       "%s: missing value",
+	"%s: %s sólo admite =, != o contiene":                                      "%s: %s only accepts =, != or contiene",
+	"%s: valor no válido para %s":                                              "%s: invalid value for %s",
+	"%s contiene secretos (%s) y lo puede leer cualquier usuario; use chmod 600 o referencias env:/file:": "%s contains secrets (%s) and is readable by any user; use chmod 600 or env:/file: references",
+	"%s lo puede leer cualquier usuario; use chmod 600 antes de guardar secretos en él":                   "%s is readable by any user; chmod 600 it before storing secrets in it",
+	"[%s] %s: la variable de entorno %s no está definida":                                                 "[%s] %s: environment variable %s is not set",
+	"[%s] %s: no se pudo leer el secreto: %v":                                                             "[%s] %s: could not read the secret: %v",
+	"[%s] %s: %s lo puede leer cualquier usuario; use chmod 600":                                          "[%s] %s: %s is readable by any user; use chmod 600",
+
+	// Cola de trabajos y API HTTP
+	"tipo de trabajo desconocido: %s":    "unknown job type: %s",
+	"falta la ruta":                      "missing path",
+	"cola llena":                         "queue full",
+	"no existe el trabajo %d":            "job %d does not exist",
+	"el trabajo %d no se puede cancelar": "job %d cannot be canceled",
+	"el trabajo %d ya ha terminado":      "job %d has already finished",
+	"token no válido":                    "invalid token",
+	"JSON no válido: ":                   "invalid JSON: ",
+	"perfil desconocido: ":               "unknown profile: ",
+	"método no permitido":                "method not allowed",
+	"trabajo no válido":                  "invalid job",
+	"no existe el trabajo":               "job does not exist",
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Idiomas de los mensajes
+const (
+	Spanish = "es"
+	English = "en"
+)
+
+// Idioma elegido; se fija al arrancar (config o LANG), antes de lanzar goroutines
+var lang = fromEnv()
+
+// catalogs tiene las traducciones de cada idioma, por el texto en español
+var catalogs = map[string]map[string]string{
+	English: english,
+}
+
+// T devuelve msg (el texto en español, tal como está en el código, con sus %s y
+// colores) en el idioma elegido. Si no hay traducción devuelve msg.
+func T(msg string) string {
+	if t, ok := catalogs[lang][msg]; ok {
+		return t
+	}
+	return msg
+}
+
+// Set fija el idioma de los mensajes: es, en o vacío para el de LANG
+func Set(l string) error {
+	l = strings.ToLower(strings.TrimSpace(l))
+	switch l {
+	case "":
+		lang = fromEnv()
+	case Spanish, English:
+		lang = l
+	default:
+		return fmt.Errorf(T("idioma desconocido %q (es o en)"), l)
+	}
+	return nil
+}
+
+// Language devuelve el idioma elegido
+func Language() string {
+	return lang
+}
+
+// fromEnv elige el idioma según LC_ALL, LC_MESSAGES o LANG (el primero que tenga
+// valor): español para es_* y las variantes de España (ca_ES, gl_ES, eu_ES) o si no
+// se indica ninguno (C, POSIX); inglés para cualquier otro
+func fromEnv() string {
+	var locale string
+	for _, v := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale = os.Getenv(v); locale != "" {
+			break
+		}
+	}
+	locale, _, _ = strings.Cut(locale, ".") // es_ES.UTF-8
+	switch {
+	case locale == "" || locale == "C" || locale == "POSIX":
+		return Spanish
+	case strings.HasPrefix(locale, "es"), strings.HasSuffix(locale, "_ES"):
+		return Spanish
+	}
+	return English
+}
@@ -0,0 +1,239 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mediacraft/config"
+	"mediacraft/encode"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/order"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Tipos de trabajo
+const (
+	TypeConvert = "convert"
+	TypeOrder   = "order"
+)
+
+// Estados de un trabajo
+const (
+	StatusQueued   = "queued"
+	StatusRunning  = "running"
+	StatusDone     = "done"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// Tamaño máximo del log guardado por trabajo (se conservan las últimas líneas)
+const maxLogSize = 256 * 1024
+
+// Job es un trabajo de conversión u ordenación en la cola
+type Job struct {
+	ID       int       `json:"id"`
+	Type     string    `json:"type"`
+	Path     string    `json:"path"`
+	Profile  string    `json:"profile,omitempty"`
+	Status   string    `json:"status"`
+	Progress float64   `json:"progress"`
+	Current  string    `json:"current,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Created  time.Time `json:"created"`
+	Started  time.Time `json:"started,omitempty"`
+	Finished time.Time `json:"finished,omitempty"`
+
+	log    []byte
+	cancel context.CancelFunc
+}
+
+// Queue es una cola de trabajos atendida por un número fijo de workers
+type Queue struct {
+	cfg     *config.Config
+	mu      sync.Mutex
+	jobs    map[int]*Job
+	nextID  int
+	pending chan *Job
+}
+
+// NewQueue crea la cola, que ejecuta los trabajos con la configuración cfg, y
+// arranca los workers
+func NewQueue(cfg *config.Config, workers int) *Queue {
+	if workers <= 0 {
+		workers = 1
+	}
+	q := &Queue{cfg: cfg, jobs: map[int]*Job{}, nextID: 1, pending: make(chan *Job, 1024)}
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+	return q
+}
+
+// Submit añade un trabajo a la cola
+func (q *Queue) Submit(jobType, path, profile string) (Job, error) {
+	if jobType != TypeConvert && jobType != TypeOrder {
+		return Job{}, fmt.Errorf(i18n.T("tipo de trabajo desconocido: %s"), jobType)
+	}
+	if path == "" {
+		return Job{}, errors.New(i18n.T("falta la ruta"))
+	}
+	q.mu.Lock()
+	j := &Job{ID: q.nextID, Type: jobType, Path: path, Profile: profile, Status: StatusQueued, Created: time.Now()}
+	q.nextID++
+	q.jobs[j.ID] = j
+	snapshot := *j
+	q.mu.Unlock()
+	select {
+	case q.pending <- j:
+		logging.Emit(logging.Event{Type: logging.EventQueued, Input: path, Profile: profile})
+	default:
+		q.finish(j, errors.New(i18n.T("cola llena")))
+		return q.mustGet(j.ID), errors.New(i18n.T("cola llena"))
+	}
+	return snapshot, nil
+}
+
+// List devuelve una copia de todos los trabajos, del más reciente al más antiguo
+func (q *Queue) List() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := make([]Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		c := *j
+		c.log = nil
+		list = append(list, c)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].ID > list[b].ID })
+	return list
+}
+
+// Get devuelve una copia del trabajo con el id indicado
+func (q *Queue) Get(id int) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	c := *j
+	c.log = nil
+	return c, true
+}
+
+// Log devuelve la salida registrada de un trabajo
+func (q *Queue) Log(id int) (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return "", false
+	}
+	return string(j.log), true
+}
+
+// Cancel cancela un trabajo en cola o en ejecución
+func (q *Queue) Cancel(id int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return fmt.Errorf(i18n.T("no existe el trabajo %d"), id)
+	}
+	switch j.Status {
+	case StatusQueued:
+		j.Status = StatusCanceled
+		j.Finished = time.Now()
+	case StatusRunning:
+		if j.cancel == nil {
+			return fmt.Errorf(i18n.T("el trabajo %d no se puede cancelar"), id)
+		}
+		j.cancel()
+	default:
+		return fmt.Errorf(i18n.T("el trabajo %d ya ha terminado"), id)
+	}
+	return nil
+}
+
+// mustGet es Get para ids que se sabe que existen
+func (q *Queue) mustGet(id int) Job {
+	j, _ := q.Get(id)
+	return j
+}
+
+// worker atiende trabajos de la cola uno a uno
+func (q *Queue) worker() {
+	for j := range q.pending {
+		ctx, cancel := context.WithCancel(context.Background())
+		q.mu.Lock()
+		if j.Status != StatusQueued { // Cancelado mientras esperaba
+			q.mu.Unlock()
+			cancel()
+			continue
+		}
+		j.Status = StatusRunning
+		j.Started = time.Now()
+		j.cancel = cancel
+		q.mu.Unlock()
+		err := q.run(ctx, j)
+		cancel()
+		q.finish(j, err)
+	}
+}
+
+// run ejecuta un trabajo
+func (q *Queue) run(ctx context.Context, j *Job) error {
+	switch j.Type {
+	case TypeOrder:
+		return order.OrderSeriesContext(ctx, q.cfg, j.Path, order.Options{})
+	default:
+		opts := encode.Options{
+			Profile: j.Profile,
+			Config:  q.cfg,
+			Log:     logWriter{q, j},
+			Progress: func(current string, percent float64) {
+				q.mu.Lock()
+				j.Current = current
+				j.Progress = percent
+				q.mu.Unlock()
+			},
+		}
+		return encode.ConvertContext(ctx, j.Path, opts)
+	}
+}
+
+// finish marca el trabajo como terminado según el error
+func (q *Queue) finish(j *Job, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j.Finished = time.Now()
+	j.cancel = nil
+	switch {
+	case errors.Is(err, context.Canceled):
+		j.Status = StatusCanceled
+	case err != nil:
+		j.Status = StatusFailed
+		j.Error = q.cfg.Redact(err.Error())
+	default:
+		j.Status = StatusDone
+		j.Progress = 100
+	}
+}
+
+// logWriter añade la salida de ffmpeg al log del trabajo
+type logWriter struct {
+	q *Queue
+	j *Job
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.q.mu.Lock()
+	defer w.q.mu.Unlock()
+	w.j.log = append(w.j.log, p...)
+	if len(w.j.log) > maxLogSize {
+		w.j.log = w.j.log[len(w.j.log)-maxLogSize:]
+	}
+	return len(p), nil
+}
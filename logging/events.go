@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Tipos de evento de --output-format json
+const (
+	EventQueued   = "queued"   // Trabajo en cola (un archivo de convert, watch o serve)
+	EventStarted  = "started"  // Empieza el trabajo
+	EventProgress = "progress" // Progreso de una salida
+	EventFinished = "finished" // Termina una salida (status ok, failed, canceled o skipped)
+	EventError    = "error"    // Falla un trabajo o una salida
+	EventMoved    = "moved"    // Se mueve un archivo (order, watch)
+	EventLog      = "log"      // Mensaje del registro
+)
+
+// Event es una línea JSON de --output-format json. Los campos vacíos se omiten.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"event"`
+	Job       string    `json:"job,omitempty"`
+	Input     string    `json:"input,omitempty"`
+	Output    string    `json:"output,omitempty"`
+	Profile   string    `json:"profile,omitempty"`
+	Position  string    `json:"position,omitempty"` // Tiempo procesado (HH:MM:SS.ms)
+	Percent   float64   `json:"percent,omitempty"`
+	Pass      int       `json:"pass,omitempty"`
+	FPS       float64   `json:"fps,omitempty"`
+	Speed     float64   `json:"speed,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Log       string    `json:"log,omitempty"` // Registro del trabajo
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Level     string    `json:"level,omitempty"`
+	Component string    `json:"component,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// SetJSON activa --output-format json: la salida estándar pasa a tener sólo eventos,
+// uno por línea, y el resto del texto va a stderr sin colores
+func SetJSON(on bool) {
+	mu.Lock()
+	jsonMode = on
+	mu.Unlock()
+}
+
+// JSON indica si está activo --output-format json
+func JSON() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return jsonMode
+}
+
+// Emit escribe el evento si está activo --output-format json; si no, no hace nada
+func Emit(e Event) {
+	mu.Lock()
+	defer mu.Unlock()
+	if jsonMode {
+		emit(e)
+	}
+}
+
+// emit escribe el evento en la salida estándar; se llama con mu bloqueado
+func emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.Encode(e)
+}
@@ -0,0 +1,305 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"mediacraft/i18n"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level es la importancia de un mensaje
+type Level int
+
+const (
+	LevelDebug Level = iota // Detalles para diagnosticar (--verbose): comandos, rutas, caché
+	LevelInfo               // Progreso normal de los trabajos
+	LevelWarn               // Avisos: el trabajo sigue
+	LevelError              // Errores
+)
+
+var levelNames = map[Level]string{LevelDebug: "DEBUG", LevelInfo: "INFO", LevelWarn: "WARN", LevelError: "ERROR"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+var (
+	mu       sync.Mutex
+	level    = LevelInfo
+	file     *os.File
+	terminal = isTerminal(os.Stdout)
+	color    = terminal && os.Getenv("NO_COLOR") == ""
+	jsonMode bool
+
+	// Líneas fijas al final de la terminal (barras de progreso, ver SetOverlay)
+	overlay      func() []string
+	overlayLines int
+	partialLine  bool // Lo último escrito no terminaba en salto de línea
+)
+
+// Secuencias de color ANSI, que no se escriben en el archivo de registro
+var ansi = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// SetLevel fija el nivel mínimo de los mensajes que se muestran en consola:
+// LevelDebug con --verbose, LevelError con --quiet
+func SetLevel(l Level) {
+	mu.Lock()
+	level = l
+	mu.Unlock()
+}
+
+// Enabled indica si los mensajes del nivel se muestran en consola
+func Enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return l >= level
+}
+
+// isTerminal indica si f es una terminal (y no un archivo, una tubería o el correo
+// de cron)
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+// Interactive indica si la salida es una terminal en la que se puede redibujar el
+// progreso. Fuera de una terminal, o con --output-format json, no se escriben los
+// fotogramas del spinner.
+func Interactive() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return terminal && !jsonMode
+}
+
+// SetColor activa o desactiva los colores. Por defecto sólo se usan en una terminal
+// y si no está definida NO_COLOR.
+func SetColor(on bool) {
+	mu.Lock()
+	color = on
+	mu.Unlock()
+}
+
+// Stdout es la salida estándar de los mensajes y las tablas: quita los colores si
+// están desactivados y, con --output-format json, escribe en stderr para que la
+// salida estándar sólo tenga eventos
+var Stdout io.Writer = stdout{}
+
+type stdout struct{}
+
+func (stdout) Write(p []byte) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return writeConsole(p)
+}
+
+// console escribe en la consola con mu ya bloqueado
+type console struct{}
+
+func (console) Write(p []byte) (int, error) {
+	return writeConsole(p)
+}
+
+// writeConsole escribe p en la consola, encima de las líneas fijas si las hay; se
+// llama con mu bloqueado
+func writeConsole(p []byte) (int, error) {
+	w := os.Stdout
+	if jsonMode {
+		w = os.Stderr
+	}
+	clearOverlay()
+	out := p
+	if !color || jsonMode {
+		out = ansi.ReplaceAll(p, nil)
+	}
+	if _, err := w.Write(out); err != nil {
+		return 0, err
+	}
+	if len(p) > 0 {
+		partialLine = p[len(p)-1] != '\n'
+	}
+	drawOverlay()
+	return len(p), nil
+}
+
+// SetOverlay fija las líneas que se dibujan al final de la terminal, debajo de los
+// mensajes: lines se llama en cada Redraw y tras cada mensaje. nil las borra. Sólo
+// tiene efecto si la salida es interactiva (ver Interactive).
+func SetOverlay(lines func() []string) {
+	mu.Lock()
+	defer mu.Unlock()
+	clearOverlay()
+	overlay = nil
+	if lines != nil && terminal && !jsonMode {
+		overlay = lines
+		drawOverlay()
+	}
+}
+
+// Redraw vuelve a dibujar las líneas de SetOverlay
+func Redraw() {
+	mu.Lock()
+	defer mu.Unlock()
+	clearOverlay()
+	drawOverlay()
+}
+
+// clearOverlay borra las líneas fijas dibujadas: sube al principio de la primera y
+// borra hasta el final de la pantalla
+func clearOverlay() {
+	if overlayLines > 0 {
+		fmt.Fprintf(os.Stdout, "\033[%dF\033[J", overlayLines)
+		overlayLines = 0
+	}
+}
+
+// drawOverlay dibuja las líneas fijas, salvo si hay una línea a medio escribir
+func drawOverlay() {
+	if overlay == nil || partialLine {
+		return
+	}
+	var sb strings.Builder
+	for _, l := range overlay() {
+		if !color {
+			l = ansi.ReplaceAllString(l, "")
+		}
+		sb.WriteString(l)
+		sb.WriteString("\033[K\n")
+		overlayLines++
+	}
+	io.WriteString(os.Stdout, sb.String())
+}
+
+// SetFile añade los mensajes de todos los niveles, con fecha, nivel y componente, al
+// archivo path (--log-file)
+func SetFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	if file != nil {
+		file.Close()
+	}
+	file = f
+	mu.Unlock()
+	return nil
+}
+
+// Close cierra el archivo de SetFile
+func Close() {
+	mu.Lock()
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+	mu.Unlock()
+}
+
+// Logger escribe los mensajes de un componente (encode, order, watch...) con sus
+// campos clave=valor
+type Logger struct {
+	component string
+	job       string
+	fields    []string
+	tee       io.Writer
+}
+
+// For devuelve el registro del componente
+func For(component string) *Logger {
+	return &Logger{component: component}
+}
+
+// With devuelve un registro que añade el campo clave=valor a cada mensaje del archivo
+func (l *Logger) With(key string, value any) *Logger {
+	v := fmt.Sprint(value)
+	if strings.ContainsAny(v, " \"=") {
+		v = fmt.Sprintf("%q", v)
+	}
+	fields := append(append([]string{}, l.fields...), key+"="+v)
+	return &Logger{component: l.component, job: l.job, fields: fields, tee: l.tee}
+}
+
+// ForJob devuelve un registro para los mensajes del trabajo id: lo añade como campo
+// trabajo=id y a los eventos de --output-format json
+func (l *Logger) ForJob(id string) *Logger {
+	j := l.With("trabajo", id)
+	j.job = id
+	return j
+}
+
+// Tee devuelve un registro que además escribe cada mensaje, sin colores, en w (p.ej.
+// el registro de un trabajo)
+func (l *Logger) Tee(w io.Writer) *Logger {
+	return &Logger{component: l.component, job: l.job, fields: l.fields, tee: w}
+}
+
+// Debugf registra detalles que sólo se muestran con --verbose
+func (l *Logger) Debugf(format string, args ...any) {
+	l.log(LevelDebug, format, args...)
+}
+
+// Infof registra el progreso normal. El mensaje se muestra tal cual (con sus colores).
+func (l *Logger) Infof(format string, args ...any) {
+	l.log(LevelInfo, format, args...)
+}
+
+// Warnf registra un aviso; en consola se muestra en amarillo con [AVISO]
+func (l *Logger) Warnf(format string, args ...any) {
+	l.log(LevelWarn, format, args...)
+}
+
+// Errorf registra un error; en consola se muestra en rojo con [ERROR]
+func (l *Logger) Errorf(format string, args ...any) {
+	l.log(LevelError, format, args...)
+}
+
+// log escribe el mensaje en consola, en --log-file y en el registro del trabajo. format
+// es el texto en español; se traduce con i18n.T antes de formatearlo.
+func (l *Logger) log(lv Level, format string, args ...any) {
+	msg := fmt.Sprintf(i18n.T(format), args...)
+	plain := strings.TrimSpace(ansi.ReplaceAllString(msg, ""))
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case lv < level:
+	case jsonMode:
+		// Con --output-format json los mensajes son eventos "log"
+		if plain != "" {
+			emit(Event{Type: EventLog, Level: strings.ToLower(lv.String()), Component: l.component, Job: l.job, Message: plain})
+		}
+	case lv == LevelDebug:
+		fmt.Fprintf(console{}, "\033[90m[DEBUG] %s\033[0m\n", strings.TrimRight(msg, "\n"))
+	case lv == LevelWarn:
+		fmt.Fprintf(console{}, "\033[33m%s %s\033[0m\n", i18n.T("[AVISO]"), strings.TrimRight(msg, "\n"))
+	case lv == LevelError:
+		fmt.Fprintf(console{}, "\033[31m[ERROR] %s\033[0m\n", strings.TrimRight(msg, "\n"))
+	default:
+		if !strings.HasSuffix(msg, "\n") {
+			msg += "\n"
+		}
+		fmt.Fprint(console{}, msg)
+	}
+	if plain == "" {
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	if l.tee != nil {
+		fmt.Fprintf(l.tee, "%s %s %s\n", now, lv, plain)
+	}
+	if file != nil {
+		line := fmt.Sprintf("%s %-5s %s: %s", now, lv, l.component, plain)
+		if len(l.fields) > 0 {
+			line += " " + strings.Join(l.fields, " ")
+		}
+		fmt.Fprintln(file, strings.ReplaceAll(line, "\n", " | "))
+	}
+}
+
+// StripColors quita las secuencias de color ANSI de s
+func StripColors(s string) string {
+	return ansi.ReplaceAllString(s, "")
+}
@@ -1,56 +1,133 @@
 package order
 
 import (
+	"context"
 	"fmt"
+	"mediacraft/config"
 	"mediacraft/decompress"
+	"mediacraft/history"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/probe"
+	"mediacraft/utils"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 )
 
-func OrderSeries(dir string) {
+var log = logging.For("order")
+
+// Options agrupa las opciones de una ordenación indicadas desde la línea de comandos
+type Options struct {
+	DryRun bool // Muestra qué se movería, sin mover nada ni registrar en el historial
+}
+
+// OrderSeries mueve los archivos de la carpeta a subcarpetas "Temporada N" y
+// registra el trabajo en el historial
+func OrderSeries(cfg *config.Config, dir string) error {
+	return OrderSeriesWith(cfg, dir, Options{})
+}
+
+// OrderSeriesWith es como OrderSeries pero con opciones adicionales
+func OrderSeriesWith(cfg *config.Config, dir string, opts Options) error {
+	return OrderSeriesContext(context.Background(), cfg, dir, opts)
+}
+
+// OrderSeriesContext es como OrderSeriesWith pero se puede cancelar con ctx: se
+// comprueba antes de cada archivo, así que los ya movidos se quedan en su carpeta
+// y se devuelve ctx.Err()
+func OrderSeriesContext(ctx context.Context, cfg *config.Config, dir string, opts Options) error {
+	if opts.DryRun {
+		return orderSeries(ctx, dir, true)
+	}
+	entry := history.New("order", dir)
+	entry.Output = dir
+	err := orderSeries(ctx, dir, false)
+	entry.Finish(err)
+	history.Record(cfg, entry)
+	return err
+}
+
+func orderSeries(ctx context.Context, dir string, dryRun bool) error {
 	// Verde: \033[32m, Azul: \033[34m, Amarillo: \033[33m, Reset: \033[0m
 	green := "\033[32m"
 	blue := "\033[34m"
 	yellow := "\033[33m"
 	reset := "\033[0m"
-	fmt.Printf("%s  Leyendo archivos de la carpeta:%s %s\n", blue, reset, dir) // nf-fa-tasks
-	// Descomprimir si es necesario
-	extracted, err := decompress.DecompressAuto(dir)
-	if err == nil && len(extracted) > 0 && (len(extracted) != 1 || extracted[0] != dir) {
-		fmt.Printf("%s  Archivos comprimidos detectados y extraídos a temporal:%s\n", yellow, reset)
-		// Si se extrajo, usar la carpeta temporal del primer archivo extraído
-		dir = filepath.Dir(extracted[0])
+	log.Infof("%s  Leyendo archivos de la carpeta:%s %s\n", blue, reset, dir) // nf-fa-tasks
+	// Descomprimir si es necesario (en un ensayo no se extrae nada)
+	if !dryRun {
+		extracted, err := decompress.DecompressAuto(dir)
+		if err == nil && len(extracted) > 0 && (len(extracted) != 1 || extracted[0] != dir) {
+			log.Infof("%s  Archivos comprimidos detectados y extraídos a temporal:%s\n", yellow, reset)
+			// Si se extrajo, usar la carpeta temporal del primer archivo extraído
+			dir = filepath.Dir(extracted[0])
+		}
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
 	}
-	files, _ := os.ReadDir(dir)
-	fmt.Printf("%s  %d archivos encontrados. Detectando temporadas...%s\n", yellow, len(files), reset) // nf-fa-file_text
+	log.Infof("%s  %d archivos encontrados. Detectando temporadas...%s\n", yellow, len(files), reset) // nf-fa-file_text
 	temporadas := make(map[string][]string)
 	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if f.IsDir() {
 			continue
 		}
 		name := f.Name()
 		temp := detectSeason(name)
+		if temp == 0 && utils.IsVideoFile(name) {
+			temp = tagSeason(filepath.Join(dir, name))
+		}
 		if temp == 0 {
 			temp = 1 // Si no se detecta, poner en Temporada 1
 		}
 		key := fmt.Sprintf("Temporada %d", temp)
 		temporadas[key] = append(temporadas[key], name)
 	}
-	fmt.Printf("\n%s  Creando carpetas y moviendo archivos...%s\n", blue, reset) // nf-fa-folder
+	log.Infof("\n%s  Creando carpetas y moviendo archivos...%s\n", blue, reset) // nf-fa-folder
+	failed := 0
 	for key, files := range temporadas {
 		tempDir := filepath.Join(dir, key)
-		os.MkdirAll(tempDir, 0755)
+		if dryRun {
+			for _, fname := range files {
+				fmt.Fprintf(logging.Stdout, i18n.T("  (ensayo) %s → %s\n"), fname, filepath.Join(key, fname))
+			}
+			continue
+		}
+		if err := os.MkdirAll(tempDir, 0755); err != nil {
+			return err
+		}
 		for _, fname := range files {
-			os.Rename(filepath.Join(dir, fname), filepath.Join(tempDir, fname))
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			from, to := filepath.Join(dir, fname), filepath.Join(tempDir, fname)
+			if err := os.Rename(from, to); err != nil {
+				log.Errorf("No se pudo mover %s: %v", fname, err)
+				failed++
+				continue
+			}
+			logging.Emit(logging.Event{Type: logging.EventMoved, From: from, To: to})
 		}
 	}
-	fmt.Printf("\n%s  Ordenación de series completada.%s\n", green, reset) // nf-fa-check
+	if failed > 0 {
+		return fmt.Errorf(i18n.T("no se pudieron mover %d archivos"), failed)
+	}
+	log.Infof("\n%s  Ordenación de series completada.%s\n", green, reset) // nf-fa-check
+	return nil
 }
 
 // detectSeason intenta extraer el número de temporada de un nombre de archivo
 func detectSeason(name string) int {
+	// Primero el analizador común (S01E02, 1x02, temporada 1 capitulo 2...)
+	if m := utils.ParseMediaName(name); m.Season > 0 {
+		return m.Season
+	}
 	name = strings.ToLower(name)
 	// Patrones comunes: S01, S1, T01, T1, 1x01, 2x05, season 1, temp1, temporada 1, etc.
 	patterns := []string{
@@ -71,6 +148,21 @@ func detectSeason(name string) int {
 	return 0
 }
 
+// tagSeason lee la temporada de las etiquetas del contenedor (season_number de MP4),
+// para los archivos cuyo nombre no la indica
+func tagSeason(path string) int {
+	info, err := probe.Probe(path)
+	if err != nil {
+		return 0
+	}
+	for k, v := range info.Format.Tags {
+		if strings.EqualFold(k, "season_number") || strings.EqualFold(k, "season") {
+			return atoiSafe(strings.TrimSpace(v))
+		}
+	}
+	return 0
+}
+
 func isDigit(b byte) bool {
 	return b >= '0' && b <= '9'
 }
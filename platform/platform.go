@@ -0,0 +1,154 @@
+package platform
+
+import (
+	"fmt"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var log = logging.For("platform")
+
+// Herramientas externas y los ejecutables que se buscan para cada una, por orden
+var toolNames = map[string][]string{
+	"ffmpeg":  {"ffmpeg"},
+	"ffprobe": {"ffprobe"},
+	"7z":      {"7z", "7za", "7zz"},
+}
+
+// Carpetas donde suelen instalarse en Windows si no están en el PATH
+var windowsDirs = map[string][]string{
+	"7z": {`C:\Program Files\7-Zip`, `C:\Program Files (x86)\7-Zip`},
+}
+
+// Rutas indicadas en [herramientas]. Son del equipo y no de cada configuración: las
+// fija una vez el programa al arrancar (ver SetToolPaths), no config.Load, para que
+// cargar otra configuración (servidor, biblioteca) no cambie las de los trabajos.
+var (
+	toolsMu    sync.Mutex
+	toolPaths  = map[string]string{}
+	toolsFound = map[string]string{}
+)
+
+// ToolNames devuelve los nombres de las herramientas externas (ffmpeg, ffprobe y 7z)
+func ToolNames() []string {
+	names := make([]string, 0, len(toolNames))
+	for n := range toolNames {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsTool indica si name es una de las herramientas externas
+func IsTool(name string) bool {
+	_, ok := toolNames[name]
+	return ok
+}
+
+// SetToolPaths fija las rutas de [herramientas]: un ejecutable o la carpeta que lo
+// contiene. Las herramientas sin ruta se buscan en el PATH.
+func SetToolPaths(paths map[string]string) {
+	toolsMu.Lock()
+	defer toolsMu.Unlock()
+	toolPaths = map[string]string{}
+	for k, v := range paths {
+		toolPaths[k] = v
+	}
+	toolsFound = map[string]string{}
+}
+
+// LookTool devuelve la ruta de la herramienta: la de [herramientas] si se indicó o
+// la primera de sus variantes que esté en el PATH (7z, 7za o 7zz)
+func LookTool(name string) (string, error) {
+	toolsMu.Lock()
+	configured, found := toolPaths[name], toolsFound[name]
+	toolsMu.Unlock()
+	if found != "" {
+		return found, nil
+	}
+	path, err := lookTool(name, configured)
+	if err != nil {
+		return "", err
+	}
+	log.Debugf("%s: %s", name, path)
+	toolsMu.Lock()
+	toolsFound[name] = path
+	toolsMu.Unlock()
+	return path, nil
+}
+
+func lookTool(name, configured string) (string, error) {
+	candidates := toolNames[name]
+	if configured != "" {
+		if st, err := os.Stat(configured); err == nil && st.IsDir() {
+			for _, c := range candidates {
+				if path, err := exec.LookPath(filepath.Join(configured, c+exeSuffix())); err == nil {
+					return path, nil
+				}
+			}
+		} else if path, err := exec.LookPath(configured); err == nil {
+			return path, nil
+		}
+		return "", fmt.Errorf(i18n.T("[herramientas] %s: no se encuentra %s"), name, configured)
+	}
+	for _, c := range candidates {
+		if path, err := exec.LookPath(c); err == nil {
+			return path, nil
+		}
+	}
+	if runtime.GOOS == "windows" {
+		for _, dir := range windowsDirs[name] {
+			for _, c := range candidates {
+				if path, err := exec.LookPath(filepath.Join(dir, c+".exe")); err == nil {
+					return path, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf(i18n.T("no se encuentra %s en el PATH (indique su ruta en [herramientas] %s)"), strings.Join(candidates, ", "), name)
+}
+
+// Tool devuelve la ruta de la herramienta o, si no se encuentra, su nombre, para que
+// el error al ejecutarla lo indique
+func Tool(name string) string {
+	if path, err := LookTool(name); err == nil {
+		return path
+	}
+	return name
+}
+
+// NullDevice devuelve el dispositivo nulo del sistema (NUL en Windows, /dev/null en
+// el resto), para las salidas que ffmpeg no debe escribir, como la primera pasada
+func NullDevice() string {
+	return os.DevNull
+}
+
+// PassLog devuelve el prefijo de los archivos de estadísticas de dos pasadas de un
+// trabajo, en la carpeta temporal: trabajos simultáneos no se pisan y no quedan
+// ffmpeg2pass-0.log en la carpeta de trabajo
+func PassLog(job string) string {
+	return filepath.Join(os.TempDir(), "mediacraft-"+job+"-2pass")
+}
+
+// RemovePassLog borra los archivos de estadísticas de PassLog (ffmpeg añade -0.log,
+// -0.log.mbtree, .temp...)
+func RemovePassLog(prefix string) {
+	files, _ := filepath.Glob(prefix + "*")
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
+
+func exeSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
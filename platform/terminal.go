@@ -0,0 +1,19 @@
+package platform
+
+import (
+	"os"
+	"strconv"
+)
+
+// TerminalWidth devuelve el ancho en columnas de la terminal de la salida estándar:
+// el que indica el sistema, COLUMNS o 80. Las líneas que se redibujan (barras de
+// progreso) no deben ser más anchas o la terminal las parte.
+func TerminalWidth() int {
+	if w := terminalWidth(os.Stdout); w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return 80
+}
@@ -0,0 +1,28 @@
+package platform
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var getConsoleScreenBufferInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleScreenBufferInfo")
+
+type coord struct {
+	x, y int16
+}
+
+type consoleScreenBufferInfo struct {
+	size, cursor             coord
+	attributes               uint16
+	left, top, right, bottom int16
+	maxSize                  coord
+}
+
+func terminalWidth(f *os.File) int {
+	var info consoleScreenBufferInfo
+	if ok, _, _ := getConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return 0
+	}
+	return int(info.right-info.left) + 1
+}
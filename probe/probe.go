@@ -0,0 +1,324 @@
+package probe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/platform"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var log = logging.For("probe")
+
+// Info es el resultado de analizar un archivo con ffprobe. La devuelve la caché, así
+// que no se debe modificar.
+type Info struct {
+	Path     string    `json:"path"`
+	Format   Format    `json:"format"`
+	Streams  []Stream  `json:"streams"`
+	Chapters []Chapter `json:"chapters,omitempty"`
+}
+
+// Format describe el contenedor
+type Format struct {
+	Name     string            `json:"name"`      // matroska,webm / mov,mp4,m4a...
+	LongName string            `json:"long_name"` // Matroska / WebM
+	Duration float64           `json:"duration"`  // Segundos (0 si no se conoce)
+	Size     int64             `json:"size"`      // Bytes
+	BitRate  float64           `json:"bit_rate"`  // Bits/s (0 si no se conoce)
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+// Stream describe una pista
+type Stream struct {
+	Index         int               `json:"index"`
+	CodecType     string            `json:"codec_type"` // video, audio, subtitle, attachment
+	CodecName     string            `json:"codec_name"`
+	Profile       string            `json:"profile,omitempty"`
+	Level         int               `json:"level,omitempty"`
+	Width         int               `json:"width,omitempty"`
+	Height        int               `json:"height,omitempty"`
+	PixFmt        string            `json:"pix_fmt,omitempty"`
+	FrameRate     float64           `json:"frame_rate,omitempty"` // Imágenes por segundo
+	HDR           string            `json:"hdr,omitempty"`        // HDR10, HDR10+, HLG, Dolby Vision o vacío
+	ColorTransfer string            `json:"color_transfer,omitempty"`
+	Channels      int               `json:"channels,omitempty"`
+	ChannelLayout string            `json:"channel_layout,omitempty"`
+	SampleRate    int               `json:"sample_rate,omitempty"`
+	BitRate       float64           `json:"bit_rate,omitempty"` // Bits/s, también de la etiqueta BPS de Matroska (0 si no se conoce)
+	Language      string            `json:"language,omitempty"` // En minúsculas (spa, eng...)
+	Title         string            `json:"title,omitempty"`
+	Disposition   map[string]int    `json:"disposition,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+// Chapter es un capítulo del archivo
+type Chapter struct {
+	ID    int64   `json:"id"`
+	Start float64 `json:"start"` // Segundos
+	End   float64 `json:"end"`
+	Title string  `json:"title,omitempty"`
+}
+
+// IsCover indica si la pista de vídeo es una portada (imagen adjunta)
+func (s Stream) IsCover() bool {
+	return s.Disposition["attached_pic"] != 0
+}
+
+// ByType devuelve las pistas de un tipo (video, audio, subtitle, attachment)
+func (i Info) ByType(codecType string) []Stream {
+	var res []Stream
+	for _, s := range i.Streams {
+		if s.CodecType == codecType {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// Video devuelve la pista de vídeo principal: la primera que no sea una portada
+func (i Info) Video() (Stream, bool) {
+	for _, s := range i.ByType("video") {
+		if !s.IsCover() {
+			return s, true
+		}
+	}
+	return Stream{}, false
+}
+
+// Caché de análisis: un archivo no se vuelve a analizar mientras no cambien su
+// tamaño ni su fecha de modificación
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]cacheEntry{}
+)
+
+type cacheEntry struct {
+	size    int64
+	modTime time.Time
+	info    *Info
+}
+
+// Probe analiza el archivo con ffprobe (formato, pistas y capítulos en una sola
+// ejecución) o devuelve el análisis guardado si el archivo no ha cambiado
+func Probe(path string) (*Info, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if st.IsDir() {
+		return nil, fmt.Errorf(i18n.T("%s es una carpeta"), path)
+	}
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+	cacheMu.Lock()
+	c, ok := cache[key]
+	cacheMu.Unlock()
+	if ok && c.size == st.Size() && c.modTime.Equal(st.ModTime()) {
+		log.Debugf("%s: análisis en caché", path)
+		return c.info, nil
+	}
+	info, err := run(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Format.Size == 0 {
+		info.Format.Size = st.Size()
+	}
+	cacheMu.Lock()
+	cache[key] = cacheEntry{st.Size(), st.ModTime(), info}
+	cacheMu.Unlock()
+	return info, nil
+}
+
+// Duration devuelve la duración del archivo en segundos (0 si no se puede analizar)
+func Duration(path string) float64 {
+	info, err := Probe(path)
+	if err != nil {
+		return 0
+	}
+	return info.Format.Duration
+}
+
+// Salida de ffprobe -print_format json
+type rawInfo struct {
+	Format struct {
+		FormatName string            `json:"format_name"`
+		LongName   string            `json:"format_long_name"`
+		Duration   string            `json:"duration"`
+		Size       string            `json:"size"`
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		Index         int               `json:"index"`
+		CodecType     string            `json:"codec_type"`
+		CodecName     string            `json:"codec_name"`
+		Profile       string            `json:"profile"`
+		Level         int               `json:"level"`
+		Width         int               `json:"width"`
+		Height        int               `json:"height"`
+		PixFmt        string            `json:"pix_fmt"`
+		AvgFrameRate  string            `json:"avg_frame_rate"`
+		RFrameRate    string            `json:"r_frame_rate"`
+		ColorTransfer string            `json:"color_transfer"`
+		Channels      int               `json:"channels"`
+		ChannelLayout string            `json:"channel_layout"`
+		SampleRate    string            `json:"sample_rate"`
+		BitRate       string            `json:"bit_rate"`
+		Disposition   map[string]int    `json:"disposition"`
+		Tags          map[string]string `json:"tags"`
+		SideDataList  []struct {
+			Type string `json:"side_data_type"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Chapters []struct {
+		ID        int64             `json:"id"`
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+}
+
+// run ejecuta ffprobe y convierte su salida
+func run(path string) (*Info, error) {
+	ffprobe, err := platform.LookTool("ffprobe")
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("ffprobe %s", path)
+	cmd := exec.Command(ffprobe, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", "-show_chapters", path)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("ffprobe: %s", msg)
+		}
+		return nil, err
+	}
+	var raw rawInfo
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf(i18n.T("ffprobe: respuesta no válida: %w"), err)
+	}
+	if len(raw.Streams) == 0 && raw.Format.FormatName == "" {
+		return nil, errors.New(i18n.T("ffprobe: no se reconoce el formato"))
+	}
+	f := raw.Format
+	info := &Info{Path: path, Format: Format{
+		Name: f.FormatName, LongName: f.LongName, Duration: number(f.Duration),
+		Size: int64(number(f.Size)), BitRate: number(f.BitRate), Tags: f.Tags,
+	}}
+	for _, s := range raw.Streams {
+		st := Stream{
+			Index: s.Index, CodecType: s.CodecType, CodecName: s.CodecName, Profile: s.Profile,
+			Level: s.Level, Width: s.Width, Height: s.Height, PixFmt: s.PixFmt,
+			ColorTransfer: s.ColorTransfer, Channels: s.Channels, ChannelLayout: s.ChannelLayout,
+			SampleRate: int(number(s.SampleRate)), Language: strings.ToLower(s.Tags["language"]),
+			Title: s.Tags["title"], Disposition: s.Disposition, Tags: s.Tags,
+		}
+		if st.CodecType == "video" {
+			if st.FrameRate = frameRate(s.AvgFrameRate); st.FrameRate == 0 {
+				st.FrameRate = frameRate(s.RFrameRate)
+			}
+			var sideData []string
+			for _, sd := range s.SideDataList {
+				sideData = append(sideData, sd.Type)
+			}
+			st.HDR = hdrFormat(s.ColorTransfer, sideData)
+			if st.HDR == "HDR10" && hdr10Plus(ffprobe, path, s.Index) {
+				st.HDR = "HDR10+"
+			}
+		}
+		// Matroska guarda la tasa de bits de cada pista en la etiqueta BPS
+		for _, v := range []string{s.BitRate, s.Tags["BPS"], s.Tags["BPS-eng"]} {
+			if st.BitRate = number(v); st.BitRate > 0 {
+				break
+			}
+		}
+		info.Streams = append(info.Streams, st)
+	}
+	for _, c := range raw.Chapters {
+		info.Chapters = append(info.Chapters, Chapter{ID: c.ID, Start: number(c.StartTime), End: number(c.EndTime), Title: c.Tags["title"]})
+	}
+	return info, nil
+}
+
+// hdrFormat deduce el tipo de HDR de la función de transferencia y de los datos
+// laterales de la pista. HDR10+ no se ve en la pista: lo comprueba hdr10Plus.
+func hdrFormat(transfer string, sideData []string) string {
+	for _, sd := range sideData {
+		if strings.Contains(sd, "DOVI") {
+			return "Dolby Vision"
+		}
+	}
+	switch transfer {
+	case "smpte2084":
+		return "HDR10"
+	case "arib-std-b67":
+		return "HLG"
+	}
+	return ""
+}
+
+// hdr10Plus indica si la primera imagen de la pista lleva metadatos dinámicos
+// SMPTE 2094-40 (HDR10+), que van en cada imagen y no en la cabecera de la pista
+func hdr10Plus(ffprobe, path string, index int) bool {
+	cmd := exec.Command(ffprobe, "-v", "error", "-print_format", "json", "-select_streams", strconv.Itoa(index),
+		"-read_intervals", "%+#1", "-show_frames", "-show_entries", "frame=side_data_list", path)
+	out, err := cmd.Output()
+	if err != nil {
+		log.Debugf("ffprobe (imágenes) %s: %v", path, err)
+		return false
+	}
+	var raw struct {
+		Frames []struct {
+			SideDataList []struct {
+				Type string `json:"side_data_type"`
+			} `json:"side_data_list"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return false
+	}
+	for _, f := range raw.Frames {
+		for _, sd := range f.SideDataList {
+			if strings.Contains(sd.Type, "SMPTE2094-40") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// frameRate convierte una fracción de ffprobe (24000/1001) en imágenes por segundo
+func frameRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		return number(s)
+	}
+	d := number(den)
+	if d == 0 {
+		return 0
+	}
+	return number(num) / d
+}
+
+// number convierte un número de ffprobe, que los da como texto (0 si no lo es)
+func number(s string) float64 {
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,288 @@
+package progress
+
+import (
+	"fmt"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/platform"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Intervalo de redibujado de las barras
+const refresh = 200 * time.Millisecond
+
+// Ancho de las barras, en caracteres
+const barWidth = 20
+
+var spinner = []rune{'⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'}
+
+// Renderer dibuja al final de la terminal una barra por cada salida en curso
+// (archivo, perfil, pasada, porcentaje, fps y tiempo restante) y, en un lote de
+// varios archivos, una barra del lote. Lo terminado y los mensajes quedan encima. Los
+// avances sólo cambian el estado: se redibuja a intervalo fijo con un time.Ticker.
+// Un *Renderer nil no muestra nada.
+type Renderer struct {
+	mu      sync.Mutex
+	tasks   []*Task
+	total   int // Archivos del lote (0 o 1: sin barra de lote)
+	done    int
+	failed  int
+	frame   int
+	started time.Time
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// New crea un renderer para un lote de total archivos
+func New(total int) *Renderer {
+	return &Renderer{total: total, started: time.Now()}
+}
+
+// Start empieza a dibujar. Fuera de una terminal, con --quiet o con
+// --output-format json no dibuja nada.
+func (r *Renderer) Start() {
+	if r == nil || r.stop != nil || !logging.Interactive() || !logging.Enabled(logging.LevelInfo) {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.stopped = make(chan struct{})
+	logging.SetOverlay(r.lines)
+	go r.loop()
+}
+
+func (r *Renderer) loop() {
+	defer close(r.stopped)
+	t := time.NewTicker(refresh)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.mu.Lock()
+			r.frame++
+			r.mu.Unlock()
+			logging.Redraw()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Stop deja de dibujar y borra las barras
+func (r *Renderer) Stop() {
+	if r == nil || r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.stopped
+	r.stop = nil
+	logging.SetOverlay(nil)
+}
+
+// active indica si se está dibujando
+func (r *Renderer) active() bool {
+	return r.stop != nil
+}
+
+// FileDone cuenta un archivo del lote como terminado
+func (r *Renderer) FileDone(ok bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.done++
+	if !ok {
+		r.failed++
+	}
+	r.mu.Unlock()
+}
+
+// Task es una salida en curso
+type Task struct {
+	r        *Renderer
+	input    string
+	profile  string
+	passes   int
+	duration float64 // Segundos de la entrada, para el tiempo restante
+	status   Status
+	started  time.Time
+}
+
+// Status es el progreso de una salida según ffmpeg
+type Status struct {
+	Pass     int     // Pasada en curso (1..passes)
+	Position string  // Tiempo procesado (HH:MM:SS.ms)
+	Percent  float64 // De la pasada en curso
+	FPS      float64
+	Speed    float64 // Velocidad respecto al tiempo real (2.5 = 2.5x)
+}
+
+// Add añade la barra de una salida de input con el perfil, sus pasadas y la duración
+// de la entrada en segundos (0 si no se conoce)
+func (r *Renderer) Add(input, profile string, passes int, duration float64) *Task {
+	if r == nil {
+		return nil
+	}
+	t := &Task{r: r, input: input, profile: profile, passes: passes, duration: duration, started: time.Now()}
+	r.mu.Lock()
+	r.tasks = append(r.tasks, t)
+	r.mu.Unlock()
+	return t
+}
+
+// Update fija el progreso de la salida; se verá en el siguiente redibujado
+func (t *Task) Update(s Status) {
+	if t == nil {
+		return
+	}
+	t.r.mu.Lock()
+	t.status = s
+	t.r.mu.Unlock()
+}
+
+// Done quita la barra de la salida y, si se está dibujando, deja encima una línea
+// con el resultado y el tiempo que ha llevado
+func (t *Task) Done(err error) {
+	if t == nil {
+		return
+	}
+	r := t.r
+	r.mu.Lock()
+	for i, x := range r.tasks {
+		if x == t {
+			r.tasks = append(r.tasks[:i], r.tasks[i+1:]...)
+			break
+		}
+	}
+	active := r.active()
+	r.mu.Unlock()
+	if !active {
+		return
+	}
+	elapsed := time.Since(t.started).Round(time.Second)
+	if err != nil {
+		fmt.Fprintf(logging.Stdout, "\033[31m✗ %s · %s · %s\033[0m\n", filepath.Base(t.input), t.profile, elapsed)
+	} else {
+		fmt.Fprintf(logging.Stdout, "\033[32m✓ %s · %s · %s\033[0m\n", filepath.Base(t.input), t.profile, elapsed)
+	}
+}
+
+// percent es el progreso de la salida contando todas sus pasadas
+func (t *Task) percent() float64 {
+	pass := t.status.Pass
+	if pass < 1 {
+		pass = 1
+	}
+	if t.passes <= 1 {
+		return t.status.Percent
+	}
+	return (float64(pass-1)*100 + t.status.Percent) / float64(t.passes)
+}
+
+// remaining estima lo que falta de la pasada en curso: por la velocidad de ffmpeg
+// o, si no la da, por lo que se ha tardado hasta ahora (0 si no se puede saber)
+func (t *Task) remaining() time.Duration {
+	s := t.status
+	if s.Percent <= 0 || s.Percent >= 100 {
+		return 0
+	}
+	if s.Speed > 0 && t.duration > 0 {
+		left := t.duration * (100 - s.Percent) / 100 / s.Speed
+		return time.Duration(left * float64(time.Second))
+	}
+	elapsed := time.Since(t.started)
+	return time.Duration(float64(elapsed) * (100 - s.Percent) / s.Percent)
+}
+
+// lines devuelve las líneas a dibujar: una por salida y la del lote
+func (r *Renderer) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	width := platform.TerminalWidth() - 1
+	spin := spinner[r.frame%len(spinner)]
+	var res []string
+	for _, t := range r.tasks {
+		parts := []string{filepath.Base(t.input), t.profile}
+		if t.passes > 1 {
+			pass := t.status.Pass
+			if pass < 1 {
+				pass = 1
+			}
+			parts = append(parts, fmt.Sprintf(i18n.T("pasada %d/%d"), pass, t.passes))
+		}
+		if t.status.FPS > 0 {
+			parts = append(parts, fmt.Sprintf("%.0f fps", t.status.FPS))
+		}
+		if t.status.Speed > 0 {
+			parts = append(parts, fmt.Sprintf("%.1fx", t.status.Speed))
+		}
+		if left := t.remaining(); left >= time.Second {
+			parts = append(parts, fmt.Sprintf(i18n.T("quedan %s"), formatRemaining(left)))
+		}
+		line := fmt.Sprintf("%c %s %5.1f%% %s", spin, bar(t.percent()), t.percent(), strings.Join(parts, " · "))
+		res = append(res, truncate(line, width))
+	}
+	if r.total > 1 {
+		sum := float64(r.done) * 100
+		for _, t := range r.tasks {
+			sum += t.percent() / float64(r.outputsOf(t.input))
+		}
+		percent := sum / float64(r.total)
+		line := fmt.Sprintf(i18n.T("  %s %5.1f%% Lote: %d/%d archivos"), bar(percent), percent, r.done, r.total)
+		if r.failed > 0 {
+			line += fmt.Sprintf(i18n.T(" · %d fallidos"), r.failed)
+		}
+		line += " · " + time.Since(r.started).Round(time.Second).String()
+		res = append(res, "\033[36m"+truncate(line, width)+"\033[0m")
+	}
+	return res
+}
+
+// outputsOf cuenta las salidas en curso de la entrada (cada una es una parte de ese archivo)
+func (r *Renderer) outputsOf(input string) int {
+	n := 0
+	for _, t := range r.tasks {
+		if t.input == input {
+			n++
+		}
+	}
+	return n
+}
+
+// bar dibuja una barra de barWidth caracteres para el porcentaje
+func bar(percent float64) string {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	full := int(percent*barWidth/100 + 0.5)
+	return "[" + strings.Repeat("█", full) + strings.Repeat("░", barWidth-full) + "]"
+}
+
+// formatRemaining muestra un tiempo restante como 1h05m, 3m10s o 45s
+func formatRemaining(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%ds", int(d.Seconds()))
+}
+
+// truncate corta la línea a width caracteres para que la terminal no la parta
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(r[:width])
+	}
+	return string(r[:width-1]) + "…"
+}
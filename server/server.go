@@ -0,0 +1,173 @@
+package server
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"mediacraft/config"
+	"mediacraft/i18n"
+	"mediacraft/jobs"
+	"mediacraft/logging"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var log = logging.For("server")
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// Server expone la cola de trabajos por HTTP
+type Server struct {
+	cfg   *config.Config
+	queue *jobs.Queue
+	token string
+}
+
+// submitRequest es el cuerpo de POST /api/jobs
+type submitRequest struct {
+	Type    string `json:"type"`
+	Path    string `json:"path"`
+	Profile string `json:"profile"`
+}
+
+// New crea un servidor con su propia cola de [servidor] workers trabajos simultáneos
+// que usan la configuración cfg
+func New(cfg *config.Config) *Server {
+	return &Server{cfg: cfg, queue: jobs.NewQueue(cfg, cfg.Server.Workers), token: cfg.Server.Token}
+}
+
+// Serve arranca el servidor HTTP en la dirección configurada ([servidor] escucha)
+func Serve(cfg *config.Config, addr string) error {
+	if addr == "" {
+		addr = cfg.Server.Listen
+	}
+	s := New(cfg)
+	log.Infof("\033[34m  MediaCraft escuchando en http://%s\033[0m\n", addr)
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+// Handler devuelve las rutas del API y del panel web
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleDashboard)
+	mux.HandleFunc("/api/profiles", s.auth(s.handleProfiles))
+	mux.HandleFunc("/api/jobs", s.auth(s.handleJobs))
+	mux.HandleFunc("/api/jobs/", s.auth(s.handleJob))
+	return mux
+}
+
+// auth exige el token configurado en la cabecera Authorization: Bearer. No se
+// admite en la URL, que queda en los registros de acceso y en el historial.
+func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, i18n.T("token no válido"))
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// GET / → panel web
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// GET /api/profiles → perfiles disponibles
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"default": s.cfg.DefaultProfile, "profiles": s.cfg.ProfileNames()})
+}
+
+// GET /api/jobs → lista; POST /api/jobs → nuevo trabajo
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.queue.List())
+	case http.MethodPost:
+		var req submitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, i18n.T("JSON no válido: ")+err.Error())
+			return
+		}
+		if req.Type == "" {
+			req.Type = jobs.TypeConvert
+		}
+		// El perfil puede ser una lista (telegram,plex,audio)
+		for _, p := range strings.Split(req.Profile, ",") {
+			if p = strings.TrimSpace(p); p != "" && !s.cfg.HasProfile(p) {
+				writeError(w, http.StatusBadRequest, i18n.T("perfil desconocido: ")+p)
+				return
+			}
+		}
+		job, err := s.queue.Submit(req.Type, req.Path, req.Profile)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, job)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, i18n.T("método no permitido"))
+	}
+}
+
+// GET /api/jobs/{id}, GET /api/jobs/{id}/log, POST /api/jobs/{id}/cancel (o DELETE /api/jobs/{id})
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		writeError(w, http.StatusNotFound, i18n.T("trabajo no válido"))
+		return
+	}
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		job, ok := s.queue.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, i18n.T("no existe el trabajo"))
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	case action == "log" && r.Method == http.MethodGet:
+		log, ok := s.queue.Log(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, i18n.T("no existe el trabajo"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(log))
+	case action == "cancel" && r.Method == http.MethodPost, action == "" && r.Method == http.MethodDelete:
+		if err := s.queue.Cancel(id); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		job, _ := s.queue.Get(id)
+		writeJSON(w, http.StatusOK, job)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, i18n.T("método no permitido"))
+	}
+}
+
+// writeJSON escribe v como respuesta JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError escribe un error JSON {"error": msg}
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,222 @@
+package targets
+
+import (
+	"fmt"
+	"mediacraft/i18n"
+	"sort"
+	"strings"
+)
+
+// VideoCodec describe un códec de vídeo admitido por un dispositivo
+type VideoCodec struct {
+	Codec    string   // Nombre del códec según ffprobe (h264, hevc, vp9, av1)
+	Profiles []string // Perfiles admitidos según ffprobe (vacío: cualquiera)
+	MaxLevel int      // Nivel máximo según ffprobe (41 = 4.1 en H.264, 153 = 5.1 en HEVC); 0 sin límite
+}
+
+// Target describe lo que un dispositivo o servicio reproduce sin transcodificar
+type Target struct {
+	Name        string
+	Description string
+	Containers  []string // Extensiones admitidas, la primera es la preferida
+	Video       []VideoCodec
+	MaxWidth    int
+	MaxHeight   int
+	PixFmts     []string
+	Audio       []string // Códecs de audio según ffprobe, el primero es el preferido
+	MaxChannels int
+	HEVCTag     bool // Requiere la etiqueta hvc1 para HEVC en MP4 (Apple)
+	FastStart   bool // Requiere el índice MP4 al principio (reproducción en streaming)
+}
+
+// Stream es la información de una pista necesaria para comprobar la compatibilidad
+type Stream struct {
+	Codec    string
+	Profile  string
+	Level    int
+	Width    int
+	Height   int
+	PixFmt   string
+	Channels int
+}
+
+// Catalog contiene los destinos de reproducción conocidos
+var Catalog = map[string]Target{
+	"plex": {
+		Name:        "plex",
+		Description: "Plex / Jellyfin direct play (clientes de TV y escritorio)",
+		Containers:  []string{".mkv", ".mp4"},
+		Video: []VideoCodec{
+			{Codec: "h264", Profiles: []string{"Baseline", "Constrained Baseline", "Main", "High"}, MaxLevel: 51},
+			{Codec: "hevc", Profiles: []string{"Main", "Main 10"}, MaxLevel: 153},
+		},
+		MaxWidth: 3840, MaxHeight: 2160,
+		PixFmts:     []string{"yuv420p", "yuv420p10le"},
+		Audio:       []string{"aac", "ac3", "eac3", "mp3", "flac", "opus"},
+		MaxChannels: 8,
+	},
+	"chromecast": {
+		Name:        "chromecast",
+		Description: "Chromecast (3ª generación, 1080p)",
+		Containers:  []string{".mp4", ".webm", ".mkv"},
+		Video: []VideoCodec{
+			{Codec: "h264", Profiles: []string{"Baseline", "Constrained Baseline", "Main", "High"}, MaxLevel: 42},
+			{Codec: "vp8"},
+		},
+		MaxWidth: 1920, MaxHeight: 1080,
+		PixFmts:     []string{"yuv420p"},
+		Audio:       []string{"aac", "mp3", "opus", "vorbis", "flac"},
+		MaxChannels: 2,
+		FastStart:   true,
+	},
+	"chromecast4k": {
+		Name:        "chromecast4k",
+		Description: "Chromecast Ultra / con Google TV (4K, HDR)",
+		Containers:  []string{".mp4", ".webm", ".mkv"},
+		Video: []VideoCodec{
+			{Codec: "h264", Profiles: []string{"Baseline", "Constrained Baseline", "Main", "High"}, MaxLevel: 51},
+			{Codec: "hevc", Profiles: []string{"Main", "Main 10"}, MaxLevel: 153},
+			{Codec: "vp9", Profiles: []string{"Profile 0", "Profile 2"}},
+		},
+		MaxWidth: 3840, MaxHeight: 2160,
+		PixFmts:     []string{"yuv420p", "yuv420p10le"},
+		Audio:       []string{"aac", "ac3", "eac3", "mp3", "opus", "vorbis", "flac"},
+		MaxChannels: 8,
+		FastStart:   true,
+	},
+	"apple": {
+		Name:        "apple",
+		Description: "iPhone, iPad, Apple TV y QuickTime",
+		Containers:  []string{".mp4", ".m4v", ".mov"},
+		Video: []VideoCodec{
+			{Codec: "h264", Profiles: []string{"Baseline", "Constrained Baseline", "Main", "High"}, MaxLevel: 52},
+			{Codec: "hevc", Profiles: []string{"Main", "Main 10"}, MaxLevel: 153},
+		},
+		MaxWidth: 3840, MaxHeight: 2160,
+		PixFmts:     []string{"yuv420p", "yuv420p10le"},
+		Audio:       []string{"aac", "ac3", "eac3", "alac"},
+		MaxChannels: 8,
+		HEVCTag:     true,
+		FastStart:   true,
+	},
+	"telegram": {
+		Name:        "telegram",
+		Description: "Telegram (reproducción en streaming en móvil y escritorio)",
+		Containers:  []string{".mp4"},
+		Video: []VideoCodec{
+			{Codec: "h264", Profiles: []string{"Baseline", "Constrained Baseline", "Main", "High"}, MaxLevel: 41},
+		},
+		MaxWidth: 1920, MaxHeight: 1080,
+		PixFmts:     []string{"yuv420p"},
+		Audio:       []string{"aac"},
+		MaxChannels: 2,
+		FastStart:   true,
+	},
+}
+
+// Get devuelve el destino con el nombre indicado
+func Get(name string) (Target, bool) {
+	t, ok := Catalog[strings.ToLower(strings.TrimSpace(name))]
+	return t, ok
+}
+
+// Names devuelve los nombres de los destinos del catálogo, ordenados
+func Names() []string {
+	names := make([]string, 0, len(Catalog))
+	for n := range Catalog {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SupportsContainer indica si el destino admite la extensión de salida
+func (t Target) SupportsContainer(ext string) bool {
+	return contains(t.Containers, strings.ToLower(ext))
+}
+
+// SupportsVideoCodec indica si el destino admite el códec de vídeo (sin mirar perfil ni nivel)
+func (t Target) SupportsVideoCodec(codec string) bool {
+	for _, v := range t.Video {
+		if v.Codec == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVideo devuelve los motivos por los que la pista de vídeo no se puede
+// reproducir directamente (vacío si es compatible)
+func (t Target) CheckVideo(s Stream) []string {
+	var problems []string
+	var codec *VideoCodec
+	for i := range t.Video {
+		if t.Video[i].Codec == s.Codec {
+			codec = &t.Video[i]
+			break
+		}
+	}
+	if codec == nil {
+		return []string{fmt.Sprintf(i18n.T("códec de vídeo %s no admitido"), s.Codec)}
+	}
+	if len(codec.Profiles) > 0 && s.Profile != "" && !contains(codec.Profiles, s.Profile) {
+		problems = append(problems, fmt.Sprintf(i18n.T("perfil %s no admitido"), s.Profile))
+	}
+	if codec.MaxLevel > 0 && s.Level > codec.MaxLevel {
+		problems = append(problems, fmt.Sprintf(i18n.T("nivel %d superior a %d"), s.Level, codec.MaxLevel))
+	}
+	if exceeds(t, s) {
+		problems = append(problems, fmt.Sprintf(i18n.T("resolución %dx%d superior a %dx%d"), s.Width, s.Height, t.MaxWidth, t.MaxHeight))
+	}
+	if len(t.PixFmts) > 0 && s.PixFmt != "" && !contains(t.PixFmts, s.PixFmt) {
+		problems = append(problems, fmt.Sprintf(i18n.T("formato de píxel %s no admitido"), s.PixFmt))
+	}
+	return problems
+}
+
+// CheckAudio devuelve los motivos por los que la pista de audio no se puede
+// reproducir directamente (vacío si es compatible)
+func (t Target) CheckAudio(s Stream) []string {
+	var problems []string
+	if !contains(t.Audio, s.Codec) {
+		problems = append(problems, fmt.Sprintf(i18n.T("códec de audio %s no admitido"), s.Codec))
+	}
+	if t.MaxChannels > 0 && s.Channels > t.MaxChannels {
+		problems = append(problems, fmt.Sprintf(i18n.T("%d canales, máximo %d"), s.Channels, t.MaxChannels))
+	}
+	return problems
+}
+
+// VideoFixes devuelve las opciones de salida necesarias al recodificar el vídeo
+// para que el resultado sea compatible: formato de píxel y escalado
+func (t Target) VideoFixes(s Stream) (opts []string, filters []string) {
+	if len(t.PixFmts) > 0 && !contains(t.PixFmts, s.PixFmt) {
+		opts = append(opts, "-pix_fmt", t.PixFmts[0])
+	}
+	if exceeds(t, s) {
+		filters = append(filters, fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease:force_divisible_by=2", t.MaxWidth, t.MaxHeight))
+	}
+	return opts, filters
+}
+
+// AudioFixes devuelve las opciones de salida necesarias al recodificar el audio
+func (t Target) AudioFixes(s Stream) []string {
+	if t.MaxChannels > 0 && s.Channels > t.MaxChannels {
+		return []string{"-ac", fmt.Sprint(t.MaxChannels)}
+	}
+	return nil
+}
+
+// exceeds indica si la pista supera la resolución máxima del destino
+func exceeds(t Target, s Stream) bool {
+	return (t.MaxWidth > 0 && s.Width > t.MaxWidth) || (t.MaxHeight > 0 && s.Height > t.MaxHeight)
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -1,3 +1,120 @@
 package utils
 
-// Aquí irán funciones auxiliares
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MediaName contiene la información deducida del nombre de un archivo de vídeo
+type MediaName struct {
+	Title   string // Nombre de la serie o película, limpio
+	Season  int    // Temporada (0 si no se detecta)
+	Episode int    // Episodio (0 si no se detecta)
+}
+
+// IsEpisode indica si el nombre corresponde a un episodio de serie
+func (m MediaName) IsEpisode() bool {
+	return m.Season > 0 && m.Episode > 0
+}
+
+// Patrones de temporada y episodio, de más a menos específico.
+// Cada patrón captura (temporada, episodio).
+var episodePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(?:^|[^a-z0-9])s(\d{1,2})[ ._-]?e(\d{1,3})`),                                                                      // S01E02, s1e2, S01.E02
+	regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(\d{1,2})x(\d{2,3})(?:[^0-9]|$)`),                                                                  // 1x02, 10x105
+	regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:season|temporada|temp)[ ._-]?(\d{1,2}).*?(?:episode|episodio|capitulo|cap|ep)[ ._-]?(\d{1,3})`), // temporada 1 capitulo 2
+	regexp.MustCompile(`(?i)(?:^|[^a-z0-9])cap[ ._-]?(\d{1,2})(\d{2})(?:[^0-9]|$)`),                                                           // Cap.102 (temporada 1, episodio 02)
+}
+
+// Patrones sólo de temporada (cuando no hay episodio)
+var seasonPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:season|temporada|temp)[ ._-]?(\d{1,2})`), // season 1, temporada 1, temp1
+	regexp.MustCompile(`(?i)(?:^|[^a-z0-9])s(\d{1,2})(?:[^0-9]|$)`),                    // S01
+}
+
+// Etiquetas habituales de releases que no forman parte del título
+var releaseTags = regexp.MustCompile(`(?i)[ ._-]\(?(?:19|20)\d{2}\)?(?:[ ._-]|$)|[ ._-](?:2160p|1080p|720p|480p|4k|uhd|web-?dl|webrip|bluray|bdrip|hdtv|x264|x265|h264|h265|hevc|xvid|dvdrip|castellano|spanish|multi)\b`)
+
+// ParseMediaName analiza el nombre de un archivo (con o sin ruta) y extrae
+// título, temporada y episodio. Reconoce S01E02, 1x02, "temporada 1 capitulo 2",
+// Cap.102 y similares.
+func ParseMediaName(path string) MediaName {
+	name := filepath.Base(strings.ReplaceAll(path, "\\", "/"))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	var m MediaName
+	titleEnd := len(name)
+	for _, re := range episodePatterns {
+		if loc := re.FindStringSubmatchIndex(name); loc != nil {
+			m.Season, _ = strconv.Atoi(name[loc[2]:loc[3]])
+			m.Episode, _ = strconv.Atoi(name[loc[4]:loc[5]])
+			titleEnd = loc[0]
+			break
+		}
+	}
+	if m.Season == 0 {
+		for _, re := range seasonPatterns {
+			if loc := re.FindStringSubmatchIndex(name); loc != nil {
+				m.Season, _ = strconv.Atoi(name[loc[2]:loc[3]])
+				titleEnd = loc[0]
+				break
+			}
+		}
+	}
+	title := name[:titleEnd]
+	if loc := releaseTags.FindStringIndex(title); loc != nil {
+		title = title[:loc[0]]
+	}
+	m.Title = cleanTitle(title)
+	if m.Title == "" {
+		m.Title = cleanTitle(name)
+	}
+	return m
+}
+
+// cleanTitle sustituye separadores por espacios y elimina corchetes y restos
+func cleanTitle(s string) string {
+	// Quitar grupos entre corchetes, p.ej. [HDTV]
+	for {
+		open := strings.Index(s, "[")
+		if open == -1 {
+			break
+		}
+		end := strings.Index(s[open:], "]")
+		if end == -1 {
+			s = s[:open]
+			break
+		}
+		s = s[:open] + " " + s[open+end+1:]
+	}
+	s = strings.NewReplacer(".", " ", "_", " ").Replace(s)
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.Trim(s, " -()")
+}
+
+// Extensiones de archivos de vídeo reconocidas
+var videoExts = map[string]bool{
+	".mkv": true, ".mp4": true, ".m4v": true, ".avi": true, ".mov": true, ".wmv": true,
+	".webm": true, ".ts": true, ".m2ts": true, ".mpg": true, ".mpeg": true, ".flv": true,
+}
+
+// IsVideoFile indica si el archivo tiene extensión de vídeo
+func IsVideoFile(path string) bool {
+	return videoExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// FormatSize muestra un tamaño en bytes de forma legible
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,385 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mediacraft/config"
+	"mediacraft/decompress"
+	"mediacraft/encode"
+	"mediacraft/i18n"
+	"mediacraft/logging"
+	"mediacraft/order"
+	"mediacraft/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var log = logging.For("watch")
+
+// item es una entrada de la carpeta vigilada: un vídeo suelto, un archivo
+// comprimido (con todos sus volúmenes) o una carpeta completa
+type item struct {
+	key      string   // Path principal (vídeo, primer volumen o carpeta)
+	paths    []string // Todos los paths que lo componen, para moverlos al terminar
+	archive  bool     // Es un archivo comprimido
+	complete bool     // Para archivos partidos: numeración de volúmenes sin huecos
+}
+
+// fileState guarda el último tamaño/fecha visto de un archivo y desde cuándo no cambia
+type fileState struct {
+	size  int64
+	mod   time.Time
+	since time.Time
+}
+
+// Extensiones de descargas a medias que nunca se procesan
+var partialExts = []string{".part", ".crdownload", ".!qb", ".!ut", ".tmp", ".partial", ".aria2"}
+
+// Watch vigila la carpeta dir (por sondeo, para que funcione en unidades de red) y
+// procesa cada entrada cuando termina de escribirse: descomprime, convierte u ordena
+// según [watch] accion, y mueve los originales a done/ o failed/. Las salidas de las
+// conversiones (output_dir, que puede ser la propia carpeta o estar dentro) no se
+// vuelven a procesar.
+func Watch(cfg *config.Config, dir string) error {
+	green := "\033[32m"
+	blue := "\033[34m"
+	reset := "\033[0m"
+	inbox, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(inbox); err != nil || !info.IsDir() {
+		return fmt.Errorf(i18n.T("no es una carpeta: %s"), dir)
+	}
+	doneDir := resolveDir(inbox, cfg.Watch.DoneDir)
+	failedDir := resolveDir(inbox, cfg.Watch.FailedDir)
+	seriesDir := resolveDir(inbox, cfg.Watch.SeriesDir)
+	for _, d := range []string{doneDir, failedDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return err
+		}
+	}
+	skip := map[string]bool{doneDir: true, failedDir: true, seriesDir: true}
+	// Sin output_dir no hay carpeta de salida que excluir: lo que se escriba en la
+	// carpeta vigilada lo recoge written
+	if outDir, err := filepath.Abs(cfg.OutputDir); err == nil && cfg.OutputDir != "" {
+		if outDir == inbox && cfg.Watch.Action != "order" {
+			log.Warnf("Las conversiones se guardan en la carpeta vigilada (output_dir): si se reinicia la vigilancia se volverán a procesar")
+		}
+		if e := inboxEntry(inbox, outDir); e != "" {
+			skip[e] = true
+		}
+	}
+	// Salidas escritas por este proceso que quedan en la propia carpeta vigilada (la
+	// entrada de primer nivel: el archivo o la subcarpeta de la plantilla)
+	written := map[string]bool{}
+	onOutput := func(p string) {
+		if abs, err := filepath.Abs(p); err == nil {
+			if e := inboxEntry(inbox, abs); e != "" {
+				written[e] = true
+			}
+		}
+	}
+	stable := time.Duration(cfg.Watch.Stable) * time.Second
+	interval := time.Duration(cfg.Watch.Interval) * time.Second
+	states := map[string]*fileState{}
+	warned := map[string]bool{}
+
+	log.Infof("%s  Vigilando %s (acción: %s, cada %s)%s\n", blue, inbox, cfg.Watch.Action, interval, reset)
+	for {
+		seen := map[string]bool{}
+		for p := range written {
+			if _, err := os.Stat(p); err != nil {
+				delete(written, p)
+			}
+		}
+		for _, it := range scan(inbox, skip, written) {
+			if !isStable(it, states, seen, stable) {
+				continue
+			}
+			if it.archive {
+				if !it.complete || decompress.TestArchive(it.key) != nil {
+					// Faltan volúmenes o el archivo aún no se puede leer: seguir esperando
+					if !warned[it.key] {
+						log.Infof("\033[33m  Esperando volúmenes de %s%s\n", filepath.Base(it.key), reset)
+						warned[it.key] = true
+					}
+					// No volver a comprobarlo hasta otro periodo de estabilidad
+					for _, p := range it.paths {
+						if st, ok := states[p]; ok {
+							st.since = time.Now()
+						}
+					}
+					continue
+				}
+			}
+			delete(warned, it.key)
+			logging.Emit(logging.Event{Type: logging.EventQueued, Input: it.key})
+			dest := doneDir
+			if err := process(cfg, it, seriesDir, onOutput); err != nil {
+				log.Errorf("%s: %s", filepath.Base(it.key), cfg.Redact(err.Error()))
+				dest = failedDir
+			} else {
+				log.Infof("%s  Procesado: %s%s\n", green, filepath.Base(it.key), reset)
+			}
+			for _, p := range it.paths {
+				if _, err := os.Stat(p); err != nil {
+					continue // Ya se movió (p.ej. vídeo ordenado en series)
+				}
+				to := uniquePath(filepath.Join(dest, filepath.Base(p)))
+				if err := moveFile(p, to); err != nil {
+					log.Errorf("No se pudo mover %s: %v", p, err)
+				} else {
+					logging.Emit(logging.Event{Type: logging.EventMoved, From: p, To: to})
+				}
+			}
+		}
+		// Olvidar archivos que ya no están
+		for p := range states {
+			if !seen[p] {
+				delete(states, p)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// resolveDir interpreta las carpetas de [watch] relativas a la carpeta vigilada
+func resolveDir(inbox, dir string) string {
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(inbox, dir)
+}
+
+// inboxEntry devuelve la entrada de primer nivel de inbox que contiene dir, o vacío si
+// dir está fuera de inbox o es inbox
+func inboxEntry(inbox, dir string) string {
+	rel, err := filepath.Rel(inbox, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	first, _, _ := strings.Cut(rel, string(filepath.Separator))
+	return filepath.Join(inbox, first)
+}
+
+// scan agrupa el contenido de primer nivel de la carpeta vigilada en entradas; omite
+// las carpetas de skip y los archivos de written
+func scan(inbox string, skip, written map[string]bool) []item {
+	entries, err := os.ReadDir(inbox)
+	if err != nil {
+		return nil
+	}
+	var items []item
+	grouped := map[string]bool{}
+	for _, e := range entries {
+		p := filepath.Join(inbox, e.Name())
+		if skip[p] || written[p] || strings.HasPrefix(e.Name(), ".") || isPartial(e.Name()) {
+			continue
+		}
+		switch {
+		case e.IsDir():
+			items = append(items, item{key: p, paths: []string{p}})
+		case decompress.IsCompressed(p):
+			parts, contiguous := decompress.ArchiveParts(p)
+			if grouped[parts[0]] {
+				continue
+			}
+			grouped[parts[0]] = true
+			items = append(items, item{key: parts[0], paths: parts, archive: true, complete: contiguous})
+		case utils.IsVideoFile(p):
+			items = append(items, item{key: p, paths: []string{p}})
+		}
+	}
+	return items
+}
+
+// isPartial indica si el archivo es una descarga a medias
+func isPartial(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range partialExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// isStable comprueba que ningún archivo de la entrada ha cambiado de tamaño ni
+// fecha durante al menos el tiempo indicado
+func isStable(it item, states map[string]*fileState, seen map[string]bool, stable time.Duration) bool {
+	now := time.Now()
+	ok := true
+	files := 0
+	for _, root := range it.paths {
+		filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				ok = false
+				return nil
+			}
+			if isPartial(p) {
+				ok = false // Carpeta con descargas a medias
+			}
+			files++
+			seen[p] = true
+			st, found := states[p]
+			if !found || st.size != info.Size() || !st.mod.Equal(info.ModTime()) {
+				states[p] = &fileState{size: info.Size(), mod: info.ModTime(), since: now}
+				ok = false
+				return nil
+			}
+			if now.Sub(st.since) < stable {
+				ok = false
+			}
+			return nil
+		})
+	}
+	return ok && files > 0
+}
+
+// process descomprime la entrada si hace falta y convierte u ordena sus vídeos;
+// onOutput recibe cada archivo convertido
+func process(cfg *config.Config, it item, seriesDir string, onOutput func(string)) error {
+	var videos, extracted []string
+	defer func() { decompress.Cleanup(extracted) }()
+	collect := func(root string) error {
+		return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if utils.IsVideoFile(p) {
+				videos = append(videos, p)
+				return nil
+			}
+			if !decompress.IsCompressed(p) {
+				return nil
+			}
+			// Sólo el primer volumen de cada archivo partido
+			if parts, _ := decompress.ArchiveParts(p); parts[0] != p {
+				return nil
+			}
+			files, err := decompress.DecompressAuto(p)
+			if err != nil {
+				return fmt.Errorf(i18n.T("no se pudo descomprimir %s: %v"), filepath.Base(p), err)
+			}
+			extracted = append(extracted, files...)
+			for _, f := range files {
+				if utils.IsVideoFile(f) {
+					videos = append(videos, f)
+				}
+			}
+			return nil
+		})
+	}
+	if err := collect(it.key); err != nil {
+		return err
+	}
+	if len(videos) == 0 {
+		return errors.New(i18n.T("no se encontraron vídeos"))
+	}
+
+	switch cfg.Watch.Action {
+	case "order":
+		if err := os.MkdirAll(seriesDir, 0755); err != nil {
+			return err
+		}
+		for _, v := range videos {
+			to := uniquePath(filepath.Join(seriesDir, filepath.Base(v)))
+			if err := moveFile(v, to); err != nil {
+				return err
+			}
+			logging.Emit(logging.Event{Type: logging.EventMoved, From: v, To: to})
+		}
+		return order.OrderSeries(cfg, seriesDir)
+	case "convert_order":
+		if err := os.MkdirAll(seriesDir, 0755); err != nil {
+			return err
+		}
+		for _, v := range videos {
+			opts := encode.Options{Profile: cfg.Watch.Profile, Output: seriesDir + string(os.PathSeparator), Config: cfg, OnOutput: onOutput}
+			if err := encode.ConvertWith(v, opts); err != nil {
+				return err
+			}
+		}
+		return order.OrderSeries(cfg, seriesDir)
+	default:
+		for _, v := range videos {
+			if err := encode.ConvertWith(v, encode.Options{Profile: cfg.Watch.Profile, Config: cfg, OnOutput: onOutput}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// uniquePath devuelve path o, si ya existe, una variante con sufijo numérico
+func uniquePath(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return path
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if _, err := os.Stat(candidate); err != nil {
+			return candidate
+		}
+	}
+}
+
+// moveFile mueve un archivo o carpeta; si el renombrado falla (distinta unidad o
+// recurso de red) copia y borra el original
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(src, p)
+			target := filepath.Join(dst, rel)
+			if d.IsDir() {
+				return os.MkdirAll(target, 0755)
+			}
+			return copyFile(p, target)
+		})
+	} else {
+		err = copyFile(src, dst)
+	}
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyFile copia el contenido de un archivo
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}